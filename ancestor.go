@@ -0,0 +1,44 @@
+package walky
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// CommonAncestor returns the deepest node under `root` (including `root`
+// itself) that contains both `a` and `b`.  If `a` is an ancestor of `b` then
+// `a` is returned, and vice versa.  Aliases are not followed, so nodes are
+// only found at their anchored location.  If either node is not found under
+// `root` then nil is returned.
+func CommonAncestor(root, a, b *yaml.Node) *yaml.Node {
+	pathA := nodeLineage(root, a, map[*yaml.Node]bool{})
+	pathB := nodeLineage(root, b, map[*yaml.Node]bool{})
+	if pathA == nil || pathB == nil {
+		return nil
+	}
+	var common *yaml.Node
+	for i := 0; i < len(pathA) && i < len(pathB); i++ {
+		if pathA[i] != pathB[i] {
+			break
+		}
+		common = pathA[i]
+	}
+	return common
+}
+
+// nodeLineage returns the list of nodes from `node` down to `target`, or nil
+// if `target` is not found.
+func nodeLineage(node, target *yaml.Node, visited map[*yaml.Node]bool) []*yaml.Node {
+	if node == nil || visited[node] {
+		return nil
+	}
+	visited[node] = true
+	if node == target {
+		return []*yaml.Node{node}
+	}
+	for _, child := range node.Content {
+		if lineage := nodeLineage(child, target, visited); lineage != nil {
+			return append([]*yaml.Node{node}, lineage...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCommonAncestor(t *testing.T) {
+	root := parse(t, `
+	spec:
+		servers:
+			- host: web1
+			  port: 80
+			- host: web2
+		volumes:
+			- data
+	`)
+	get := func(path ...interface{}) *yaml.Node {
+		var found *yaml.Node
+		err := walky.WalkPath(root, func(n *yaml.Node) error {
+			found = n
+			return nil
+		}, path...)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		return found
+	}
+
+	host1 := get("spec", "servers", 0, "host")
+	port1 := get("spec", "servers", 0, "port")
+	host2 := get("spec", "servers", 1, "host")
+	volume := get("spec", "volumes", 0)
+
+	require.Same(t, get("spec", "servers", 0), walky.CommonAncestor(root, host1, port1))
+	require.Same(t, get("spec", "servers"), walky.CommonAncestor(root, host1, host2))
+	require.Same(t, get("spec"), walky.CommonAncestor(root, host1, volume))
+	require.Same(t, get("spec", "servers"), walky.CommonAncestor(root, get("spec", "servers"), host2))
+	require.Same(t, host1, walky.CommonAncestor(root, host1, host1))
+
+	require.Nil(t, walky.CommonAncestor(root, host1, walky.NewStringNode("web1")))
+	require.Nil(t, walky.CommonAncestor(get("spec", "volumes"), host1, volume))
+}
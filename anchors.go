@@ -0,0 +1,175 @@
+package walky
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckAnchorOrder verifies that every AliasNode in `root` references an anchor
+// that has been declared earlier in the document.  Documents decoded by
+// yaml.v3 will always pass, but programmatically constructed documents can
+// contain aliases that reference an anchor declared later, or not at all, which
+// will produce invalid YAML when marshaled.  A YAMLErrors list is returned with
+// an error for each invalid alias.
+func CheckAnchorOrder(root *yaml.Node) error {
+	declared := map[string]bool{}
+	pending := []*yaml.Node{}
+	check := func(node *yaml.Node) error {
+		if node.Anchor != "" {
+			declared[node.Anchor] = true
+		}
+		if node.Kind == yaml.AliasNode && !declared[node.Value] {
+			pending = append(pending, node)
+		}
+		return nil
+	}
+	err := Walk(root, allNodesWalker(check))
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	errs := YAMLErrors{}
+	for _, alias := range pending {
+		err := fmt.Errorf("alias references undeclared anchor %q", alias.Value)
+		if declared[alias.Value] {
+			err = fmt.Errorf("alias references anchor %q before it is declared", alias.Value)
+		}
+		errs = append(errs, NewYAMLError(err, alias).(YAMLError))
+	}
+	return errs
+}
+
+// AliasInfo describes an alias node found by Aliases.
+type AliasInfo struct {
+	// Node is the AliasNode.
+	Node *yaml.Node
+	// Anchor is the name of the anchor referenced by the alias.
+	Anchor string
+	// Target is the node the alias resolves to (following any chained
+	// aliases), or nil if the alias is dangling.
+	Target *yaml.Node
+	// Dangling is true if the alias does not resolve to a node.
+	Dangling bool
+}
+
+// Aliases returns information about every AliasNode found in `root` in
+// document order.
+func Aliases(root *yaml.Node) []AliasInfo {
+	aliases := []AliasInfo{}
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if node.Kind != yaml.AliasNode {
+			return nil
+		}
+		target := node.Alias
+		for target != nil && target.Kind == yaml.AliasNode {
+			target = target.Alias
+		}
+		aliases = append(aliases, AliasInfo{
+			Node:     node,
+			Anchor:   node.Value,
+			Target:   target,
+			Dangling: target == nil,
+		})
+		return nil
+	}))
+	return aliases
+}
+
+// AnchorUsage returns the node in `root` that defines the anchor `name`
+// along with every AliasNode that references it, in document order.  If the
+// anchor is defined more than once the first definition is returned.  The
+// returned bool is false if the anchor is not defined, in which case any
+// (dangling) aliases referencing `name` are still returned.
+func AnchorUsage(root *yaml.Node, name string) (def *yaml.Node, uses []*yaml.Node, ok bool) {
+	uses = []*yaml.Node{}
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if node.Anchor == name && def == nil {
+			def = node
+		}
+		if node.Kind == yaml.AliasNode && node.Value == name {
+			uses = append(uses, node)
+		}
+		return nil
+	}))
+	return def, uses, def != nil
+}
+
+// FindAnchor returns the first node in `root` with the anchor `name`, or nil
+// if there is no such anchor.  Nodes are visited in document order (depth
+// first, with each map key visited before its value) and aliases are not
+// followed, so if the anchor is defined more than once the definition nearest
+// the start of the document is returned.
+func FindAnchor(root *yaml.Node, name string) *yaml.Node {
+	def, _, _ := AnchorUsage(root, name)
+	return def
+}
+
+// FindAllAnchors returns every anchored node in `root` by anchor name.
+// Nodes are visited in the same order as FindAnchor, so if an anchor is
+// defined more than once the first definition is returned.
+func FindAllAnchors(root *yaml.Node) map[string]*yaml.Node {
+	anchors := map[string]*yaml.Node{}
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if _, ok := anchors[node.Anchor]; node.Anchor != "" && !ok {
+			anchors[node.Anchor] = node
+		}
+		return nil
+	}))
+	return anchors
+}
+
+// SetAnchor sets the anchor of `node` to `name`, so the node can be referenced
+// by aliases.  An empty `name` removes the anchor.
+// Existing aliases are not updated, use RenameAnchor to rename an anchor
+// that is already referenced.
+func SetAnchor(node *yaml.Node, name string) {
+	node.Anchor = name
+}
+
+// RenameAnchor renames the anchor `old` to `new` in `root`, updating the
+// anchor definition and every AliasNode that references it.  The number of
+// aliases updated is returned.  An error is returned if `old` is not defined,
+// or if `new` is empty or already defined in `root`, in which case nothing is
+// modified.
+func RenameAnchor(root *yaml.Node, old, new string) (int, error) {
+	if old == "" {
+		return 0, fmt.Errorf("anchor %q not found", old)
+	}
+	if new == "" {
+		return 0, fmt.Errorf("invalid empty anchor name")
+	}
+	var defs, uses []*yaml.Node
+	var collision *yaml.Node
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		switch {
+		case node.Anchor == old:
+			defs = append(defs, node)
+		case node.Anchor == new && collision == nil:
+			collision = node
+		}
+		if node.Kind == yaml.AliasNode && node.Value == old {
+			uses = append(uses, node)
+		}
+		return nil
+	}))
+	if len(defs) == 0 {
+		return 0, fmt.Errorf("anchor %q not found", old)
+	}
+	if collision != nil && old != new {
+		return 0, Errorf(collision, "anchor %q already defined", new)
+	}
+	for _, def := range defs {
+		def.Anchor = new
+	}
+	for _, use := range uses {
+		use.Value = new
+	}
+	return len(uses), nil
+}
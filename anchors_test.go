@@ -0,0 +1,214 @@
+package walky_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckAnchorOrder(t *testing.T) {
+	doc := HereBytes(`
+	defs: &defs
+		a: 1
+	uses:
+		- *defs
+		- b: *defs
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+	require.NoError(t, walky.CheckAnchorOrder(&root))
+
+	target := walky.NewStringNode("value")
+	target.Anchor = "later"
+	forward := &yaml.Node{
+		Kind:   yaml.AliasNode,
+		Value:  "later",
+		Alias:  target,
+		Line:   2,
+		Column: 8,
+	}
+	missing := &yaml.Node{
+		Kind:   yaml.AliasNode,
+		Value:  "missing",
+		Line:   4,
+		Column: 8,
+	}
+	built := walky.NewMappingNode()
+	built.Content = append(built.Content,
+		walky.NewStringNode("first"), forward,
+		walky.NewStringNode("second"), target,
+		walky.NewStringNode("third"), missing,
+	)
+
+	err = walky.CheckAnchorOrder(built)
+	require.Error(t, err)
+	var errs walky.YAMLErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, 2, errs[0].Line)
+	require.EqualError(t, errs[0], `line 2:8 at "later": alias references anchor "later" before it is declared`)
+	require.EqualError(t, errs[1], `line 4:8 at "missing": alias references undeclared anchor "missing"`)
+}
+
+func TestAliases(t *testing.T) {
+	doc := HereBytes(`
+	defs:
+		base: &base {a: 1}
+		name: &name web
+	app:
+		config: *base
+		names: [*name, other, *name]
+		<<: *base
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	dangling := &yaml.Node{Kind: yaml.AliasNode, Value: "missing"}
+	err = walky.AssignMapNode(walky.GetKey(&root, "app"), walky.NewStringNode("broken"), dangling)
+	require.NoError(t, err)
+
+	base := walky.GetKey(walky.GetKey(&root, "defs"), "base")
+	name := walky.GetKey(walky.GetKey(&root, "defs"), "name")
+
+	type info struct {
+		Line     int
+		Anchor   string
+		Target   *yaml.Node
+		Dangling bool
+	}
+	got := []info{}
+	for _, a := range walky.Aliases(&root) {
+		require.Equal(t, yaml.AliasNode, a.Node.Kind)
+		got = append(got, info{a.Node.Line, a.Anchor, a.Target, a.Dangling})
+	}
+	// AssignMapNode inserted `broken` alphabetically before `config`
+	require.Equal(t, []info{
+		{0, "missing", nil, true},
+		{5, "base", base, false},
+		{6, "name", name, false},
+		{6, "name", name, false},
+		{7, "base", base, false},
+	}, got)
+}
+
+func TestAnchorUsage(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		timeout: 30
+	other: &other 1
+	web:
+		<<: *defaults
+		port: 80
+	db:
+		<<: *defaults
+		retries: [*other]
+	copy: *defaults
+	`)
+	def, uses, ok := walky.AnchorUsage(root, "defaults")
+	require.True(t, ok)
+	require.Equal(t, yaml.MappingNode, def.Kind)
+	require.Equal(t, 1, def.Line)
+	lines := []int{}
+	for _, use := range uses {
+		require.Equal(t, yaml.AliasNode, use.Kind)
+		require.Same(t, def, use.Alias)
+		lines = append(lines, use.Line)
+	}
+	require.Equal(t, []int{5, 8, 10}, lines)
+
+	def, uses, ok = walky.AnchorUsage(root, "other")
+	require.True(t, ok)
+	require.Equal(t, "1", def.Value)
+	require.Len(t, uses, 1)
+
+	def, uses, ok = walky.AnchorUsage(root, "missing")
+	require.False(t, ok)
+	require.Nil(t, def)
+	require.Empty(t, uses)
+}
+
+func TestFindAnchor(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: &image web:1.0
+	&key keyed: value
+	services:
+		- &web {name: web}
+		- *web
+	redefined: &image api:1.0
+	`)
+	require.Equal(t, "image", walky.FindAnchor(root, "defaults").Content[0].Value)
+	require.Equal(t, "web:1.0", walky.FindAnchor(root, "image").Value)
+	require.Equal(t, "keyed", walky.FindAnchor(root, "key").Value)
+	require.Equal(t, yaml.MappingNode, walky.FindAnchor(root, "web").Kind)
+	require.Nil(t, walky.FindAnchor(root, "missing"))
+
+	anchors := walky.FindAllAnchors(root)
+	require.Len(t, anchors, 4)
+	for name, node := range anchors {
+		require.Same(t, walky.FindAnchor(root, name), node, name)
+	}
+	require.Equal(t, 2, anchors["image"].Line)
+
+	require.Empty(t, walky.FindAllAnchors(parse(t, `a: 1`)))
+}
+
+func TestRenameAnchor(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		timeout: 30
+	other: &other 1
+	web:
+		config: *defaults
+	db:
+		config: *defaults
+		other: *other
+	`)
+	count, err := walky.RenameAnchor(root, "defaults", "base")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		defaults: &base
+			timeout: 30
+		other: &other 1
+		web:
+			config: *base
+		db:
+			config: *base
+			other: *other
+	`), string(got))
+
+	_, err = walky.RenameAnchor(root, "base", "other")
+	require.EqualError(t, err, `line 3:8 at "1": anchor "other" already defined`)
+	_, err = walky.RenameAnchor(root, "missing", "new")
+	require.EqualError(t, err, `anchor "missing" not found`)
+	_, err = walky.RenameAnchor(root, "base", "")
+	require.EqualError(t, err, `invalid empty anchor name`)
+
+	// renaming to the same name is a no-op
+	count, err = walky.RenameAnchor(root, "base", "base")
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	node := walky.GetKey(root, "web")
+	walky.SetAnchor(node, "web")
+	walky.GetKey(root, "db").Content = append(walky.GetKey(root, "db").Content,
+		walky.NewStringNode("web"), &yaml.Node{Kind: yaml.AliasNode, Value: "web", Alias: node},
+	)
+	require.NoError(t, walky.CheckAnchorOrder(root))
+	count, err = walky.RenameAnchor(root, "web", "frontend")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+	require.Equal(t, "frontend", node.Anchor)
+
+	walky.SetAnchor(walky.GetKey(root, "defaults"), "")
+	_, err = walky.RenameAnchor(root, "base", "again")
+	require.EqualError(t, err, `anchor "base" not found`)
+}
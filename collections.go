@@ -0,0 +1,96 @@
+package walky
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// NewSetNode creates a new `!!set` Node, which is a mapping where each of the
+// `members` is a key with a null value.  Note that yaml.v3 will marshal the
+// set members in the implicit key form (`member:`) rather than the explicit
+// key form (`? member`), both decode to the same set.
+func NewSetNode(members ...*yaml.Node) *yaml.Node {
+	node := &yaml.Node{
+		Kind: yaml.MappingNode,
+		Tag:  "!!set",
+	}
+	for _, member := range members {
+		node.Content = append(node.Content, member, &yaml.Node{
+			Kind: yaml.ScalarNode,
+			Tag:  "!!null",
+		})
+	}
+	return node
+}
+
+// IsSet will return true if the node (after resolving aliases) is a mapping
+// with the `!!set` tag.
+func IsSet(node *yaml.Node) bool {
+	node = Indirect(node)
+	return node.Kind == yaml.MappingNode && node.Tag == "!!set"
+}
+
+// SetMembers returns the members of the `!!set` node, or nil if the node is
+// not a set.
+func SetMembers(node *yaml.Node) []*yaml.Node {
+	if !IsSet(node) {
+		return nil
+	}
+	node = Indirect(node)
+	members := make([]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		members = append(members, node.Content[i])
+	}
+	return members
+}
+
+// NewOMapNode creates a new empty `!!omap` Node, which is a sequence of
+// single key mappings.  Use OMapSet and OMapGet to modify and query the
+// ordered map.
+func NewOMapNode() *yaml.Node {
+	return &yaml.Node{
+		Kind: yaml.SequenceNode,
+		Tag:  "!!omap",
+	}
+}
+
+// IsOMap will return true if the node (after resolving aliases) is a sequence
+// with the `!!omap` tag.
+func IsOMap(node *yaml.Node) bool {
+	node = Indirect(node)
+	return node.Kind == yaml.SequenceNode && node.Tag == "!!omap"
+}
+
+// OMapSet will update the value for `keyNode` in the `!!omap` node if the key
+// already exists (preserving the key position), otherwise a new single key
+// mapping with `keyNode` and `valNode` is appended.  An error is returned if
+// the node is not an ordered map.
+func OMapSet(omapNode, keyNode, valNode *yaml.Node) error {
+	if !IsOMap(omapNode) {
+		return Errorf(omapNode, "OMapSet called on invalid type: %s", omapNode.Tag)
+	}
+	omapNode = Indirect(omapNode)
+	for _, pair := range omapNode.Content {
+		if _, value := GetKeyValue(pair, keyNode); value != nil {
+			AssignNode(value, valNode)
+			return nil
+		}
+	}
+	pair := NewMappingNode()
+	pair.Content = append(pair.Content, keyNode, valNode)
+	omapNode.Content = append(omapNode.Content, pair)
+	return nil
+}
+
+// OMapGet returns the value for `key` from the `!!omap` node, or nil if the
+// key is not found or the node is not an ordered map.
+func OMapGet(omapNode *yaml.Node, key interface{}) *yaml.Node {
+	if !IsOMap(omapNode) {
+		return nil
+	}
+	for _, pair := range Indirect(omapNode).Content {
+		if value := GetKey(pair, key); value != nil {
+			return value
+		}
+	}
+	return nil
+}
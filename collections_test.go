@@ -0,0 +1,91 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetNode(t *testing.T) {
+	set := walky.NewSetNode(
+		walky.NewStringNode("beta"),
+		walky.NewStringNode("dark-mode"),
+	)
+	require.True(t, walky.IsSet(set))
+	require.False(t, walky.IsSet(walky.NewMappingNode()))
+	require.Nil(t, walky.SetMembers(walky.NewMappingNode()))
+
+	hasMember := func(set *yaml.Node, member string) bool {
+		for _, m := range walky.SetMembers(set) {
+			if walky.Equal(m, walky.NewStringNode(member)) {
+				return true
+			}
+		}
+		return false
+	}
+	require.True(t, hasMember(set, "beta"))
+	require.True(t, hasMember(set, "dark-mode"))
+	require.False(t, hasMember(set, "gamma"))
+
+	root := walky.NewMappingNode()
+	err := walky.AssignMapNode(root, walky.NewStringNode("flags"), set)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		flags: !!set
+			beta:
+			dark-mode:
+	`), string(got))
+
+	decoded := parse(t, `
+	flags: !!set
+		? beta
+		? dark-mode
+	`)
+	flags := walky.GetKey(decoded, "flags")
+	require.True(t, walky.IsSet(flags))
+	require.True(t, walky.Equal(set, flags))
+	require.Len(t, walky.SetMembers(flags), 2)
+}
+
+func TestOMapNode(t *testing.T) {
+	omap := walky.NewOMapNode()
+	require.True(t, walky.IsOMap(omap))
+	require.False(t, walky.IsOMap(walky.NewSequenceNode()))
+
+	for _, kv := range []struct {
+		Key   string
+		Value int64
+	}{{"zeta", 1}, {"alpha", 2}, {"mid", 3}, {"alpha", 4}} {
+		err := walky.OMapSet(omap, walky.NewStringNode(kv.Key), walky.NewIntNode(kv.Value))
+		require.NoError(t, err)
+	}
+	require.Equal(t, "4", walky.OMapGet(omap, "alpha").Value)
+	require.Equal(t, "1", walky.OMapGet(omap, "zeta").Value)
+	require.Nil(t, walky.OMapGet(omap, "missing"))
+
+	root := walky.NewMappingNode()
+	err := walky.AssignMapNode(root, walky.NewStringNode("order"), omap)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	expected := Here(`
+		order: !!omap
+			- zeta: 1
+			- alpha: 4
+			- mid: 3
+	`)
+	require.Equal(t, expected, string(got))
+
+	decoded := parse(t, expected)
+	order := walky.GetKey(decoded, "order")
+	require.True(t, walky.IsOMap(order))
+	require.True(t, walky.Equal(omap, order))
+	require.Equal(t, "3", walky.OMapGet(order, "mid").Value)
+
+	err = walky.OMapSet(walky.NewSequenceNode(), walky.NewStringNode("a"), walky.NewIntNode(1))
+	require.EqualError(t, err, ": OMapSet called on invalid type: !!seq")
+}
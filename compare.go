@@ -0,0 +1,171 @@
+package walky
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SameShape will return true if `a` and `b` have the same structure while
+// ignoring scalar values (and tags).  Mappings must have the same set of keys
+// (including keys from `!!merge` sources) with values of the same shape, and
+// sequences must have the same length with elements of the same shape.
+// Aliases are resolved and documents unwrapped before comparing.
+func SameShape(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	a, b = Indirect(a), Indirect(b)
+	if a.Kind != b.Kind {
+		return false
+	}
+	switch a.Kind {
+	case yaml.MappingNode:
+		aPairs, bPairs := mapPairs(a), mapPairs(b)
+		if len(aPairs) != len(bPairs) {
+			return false
+		}
+	OUTER:
+		for i := 0; i < len(aPairs); i += 2 {
+			for j := 0; j < len(bPairs); j += 2 {
+				if Equal(aPairs[i], bPairs[j]) {
+					if !SameShape(aPairs[i+1], bPairs[j+1]) {
+						return false
+					}
+					continue OUTER
+				}
+			}
+			return false
+		}
+	case yaml.SequenceNode, yaml.DocumentNode:
+		if len(a.Content) != len(b.Content) {
+			return false
+		}
+		for i := range a.Content {
+			if !SameShape(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// mapPairs returns the key/value pairs of the mapping `node` as visited by
+// RangeMap, so `!!merge` keys are expanded.
+func mapPairs(node *yaml.Node) []*yaml.Node {
+	pairs := []*yaml.Node{}
+	// errors are ignored since we only call this for valid mapping
+	// nodes
+	_ = RangeMap(node, func(key, value *yaml.Node) error {
+		pairs = append(pairs, key, value)
+		return nil
+	})
+	return pairs
+}
+
+// EqualDepth is the same as Equal, but will only compare nodes down to
+// `maxDepth` levels below `a` and `b`.  With a `maxDepth` of 0 only the kind,
+// tag, value and content length of `a` and `b` are compared, with a `maxDepth`
+// of 1 the immediate children are also compared, and so on.  Content below
+// `maxDepth` is assumed to be equal, so EqualDepth can return true for nodes
+// that are not Equal; it is intended as a cheap filter before calling Equal.
+// If `maxDepth` is negative this is the same as Equal.
+func EqualDepth(a, b *yaml.Node, maxDepth int) bool {
+	return equal(a, b, maxDepth)
+}
+
+// EqualResolved is the same as Equal, but aliases are inlined and `!!merge`
+// keys are expanded in both `a` and `b` before comparing.  This allows a
+// document using anchors and merges to be compared with the equivalent
+// document where all values are inlined.  If either node contains an invalid
+// merge (ie merging a scalar) then false is returned.
+func EqualResolved(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	resolvedA, err := resolveNode(a, map[*yaml.Node]bool{})
+	if err != nil {
+		return false
+	}
+	resolvedB, err := resolveNode(b, map[*yaml.Node]bool{})
+	if err != nil {
+		return false
+	}
+	return Equal(resolvedA, resolvedB)
+}
+
+// EqualUnordered is the same as Equal, but sequences are compared without
+// regard to the order of their elements (at every level).  Each element of
+// `a` must match a distinct element of `b`, so the sequences must contain the
+// same elements with the same multiplicity.
+func EqualUnordered(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	a, b = Indirect(a), Indirect(b)
+	if !equal(a, b, 0) {
+		return false
+	}
+	switch a.Kind {
+	case yaml.MappingNode:
+		aContent := make([]*yaml.Node, len(a.Content))
+		bContent := make([]*yaml.Node, len(b.Content))
+		copy(aContent, a.Content)
+		copy(bContent, b.Content)
+		sort.Sort(sortableNodeMap(aContent))
+		sort.Sort(sortableNodeMap(bContent))
+		for i := 0; i < len(aContent); i += 2 {
+			if !Equal(aContent[i], bContent[i]) || !EqualUnordered(aContent[i+1], bContent[i+1]) {
+				return false
+			}
+		}
+	case yaml.SequenceNode:
+		used := make([]bool, len(b.Content))
+	OUTER:
+		for _, aElem := range a.Content {
+			for j, bElem := range b.Content {
+				if !used[j] && EqualUnordered(aElem, bElem) {
+					used[j] = true
+					continue OUTER
+				}
+			}
+			return false
+		}
+	default:
+		for i := range a.Content {
+			if !EqualUnordered(a.Content[i], b.Content[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// EqualWithStyle is the same as Equal, but also requires the Style of every
+// node to match, so a literal block string (`|`) is not equal to a plain or
+// quoted string with the same value, and a flow sequence is not equal to a
+// block sequence.  Comments and positions are still ignored.
+func EqualWithStyle(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	a, b = Indirect(a), Indirect(b)
+	if a.Style != b.Style || !equal(a, b, 0) {
+		return false
+	}
+	aContent, bContent := a.Content, b.Content
+	if a.Kind == yaml.MappingNode {
+		aContent = make([]*yaml.Node, len(a.Content))
+		bContent = make([]*yaml.Node, len(b.Content))
+		copy(aContent, a.Content)
+		copy(bContent, b.Content)
+		sort.Sort(sortableNodeMap(aContent))
+		sort.Sort(sortableNodeMap(bContent))
+	}
+	for i := range aContent {
+		if !EqualWithStyle(aContent[i], bContent[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,273 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func parse(t *testing.T, doc string) *yaml.Node {
+	t.Helper()
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(Here(doc)), &root)
+	require.NoError(t, err)
+	return &root
+}
+
+func TestSameShape(t *testing.T) {
+	base := parse(t, `
+	name: web
+	replicas: 3
+	ports: [80, 443]
+	env:
+		DEBUG: "false"
+	`)
+
+	for _, tt := range []struct {
+		Name     string
+		Doc      string
+		Expected bool
+	}{{
+		Name: src(),
+		Doc: `
+		env: {DEBUG: "true"}
+		replicas: five
+		name: api
+		ports: [8080, 8443]
+		`,
+		Expected: true,
+	}, {
+		Name: src(),
+		Doc: `
+		defaults: &defaults
+			replicas: 1
+			ports: [1, 2]
+		`,
+		Expected: false,
+	}, {
+		Name: src(),
+		Doc: `
+		name: web
+		replicas: 3
+		ports: [80, 443]
+		env:
+			DEBUG: "false"
+		extra: key
+		`,
+		Expected: false,
+	}, {
+		Name: src(),
+		Doc: `
+		name: web
+		replicas: 3
+		ports: [80]
+		env:
+			DEBUG: "false"
+		`,
+		Expected: false,
+	}, {
+		Name: src(),
+		Doc: `
+		name: web
+		replicas: 3
+		ports: [80, 443]
+		env:
+			VERBOSE: "false"
+		`,
+		Expected: false,
+	}, {
+		Name: src(),
+		Doc: `
+		name: web
+		replicas: {count: 3}
+		ports: [80, 443]
+		env:
+			DEBUG: "false"
+		`,
+		Expected: false,
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			require.Equal(t, tt.Expected, walky.SameShape(base, parse(t, tt.Doc)))
+		})
+	}
+
+	merged := parse(t, `
+	defaults: &defaults
+		name: api
+		replicas: 1
+	app:
+		<<: *defaults
+		ports: [1, 2]
+		env: {DEBUG: "true"}
+	`)
+	require.True(t, walky.SameShape(base, walky.GetKey(merged, "app")))
+}
+
+func TestEqualDepth(t *testing.T) {
+	a := parse(t, `
+	name: web
+	spec:
+		replicas: 3
+		template:
+			image: web:1.0
+	`)
+	b := parse(t, `
+	name: web
+	spec:
+		replicas: 3
+		template:
+			image: web:2.0
+	`)
+	c := parse(t, `
+	name: api
+	spec:
+		replicas: 3
+		template:
+			image: web:1.0
+	`)
+
+	require.False(t, walky.Equal(a, b))
+	for depth, expected := range []bool{true, true, true, false} {
+		require.Equal(t, expected, walky.EqualDepth(a, b, depth), "depth %d", depth)
+	}
+	require.False(t, walky.EqualDepth(a, b, -1))
+
+	require.True(t, walky.EqualDepth(a, c, 0))
+	require.False(t, walky.EqualDepth(a, c, 1))
+	require.True(t, walky.EqualDepth(a, a, 10))
+}
+
+func TestEqualResolved(t *testing.T) {
+	dry := parse(t, `
+	defaults: &defaults
+		image: app:1.0
+		ports: &ports [80, 443]
+	web:
+		<<: *defaults
+		name: web
+	api:
+		<<: *defaults
+		name: api
+		image: api:2.0
+		extra: *ports
+	`)
+	expanded := parse(t, `
+	defaults:
+		image: app:1.0
+		ports: [80, 443]
+	web:
+		image: app:1.0
+		ports: [80, 443]
+		name: web
+	api:
+		name: api
+		image: api:2.0
+		ports: [80, 443]
+		extra: [80, 443]
+	`)
+	require.False(t, walky.Equal(dry, expanded))
+	require.True(t, walky.EqualResolved(dry, expanded))
+	require.True(t, walky.EqualResolved(expanded, dry))
+
+	changed := parse(t, `
+	defaults:
+		image: app:1.0
+		ports: [80, 443]
+	web:
+		image: app:1.0
+		ports: [80, 443]
+		name: web
+	api:
+		name: api
+		image: app:1.0
+		ports: [80, 443]
+		extra: [80, 443]
+	`)
+	require.False(t, walky.EqualResolved(dry, changed))
+
+	invalid := parse(t, `
+	a: &a 1
+	b:
+		<<: *a
+	`)
+	require.False(t, walky.EqualResolved(invalid, invalid))
+}
+
+func TestEqualUnordered(t *testing.T) {
+	a := parse(t, `
+	tags: [web, prod, us-east]
+	rules:
+		- {port: 80, hosts: [a, b]}
+		- {port: 443, hosts: [c]}
+	`)
+	b := parse(t, `
+	rules:
+		- {port: 443, hosts: [c]}
+		- {hosts: [b, a], port: 80}
+	tags: [us-east, web, prod]
+	`)
+	require.False(t, walky.Equal(a, b))
+	require.True(t, walky.EqualUnordered(a, b))
+	require.True(t, walky.EqualUnordered(b, a))
+
+	require.True(t, walky.EqualUnordered(parse(t, `[a, a, b]`), parse(t, `[a, b, a]`)))
+	require.False(t, walky.EqualUnordered(parse(t, `[a, a, b]`), parse(t, `[a, b, b]`)))
+	require.False(t, walky.EqualUnordered(parse(t, `[a, b]`), parse(t, `[a, b, c]`)))
+	require.False(t, walky.EqualUnordered(parse(t, `{a: [1, 2]}`), parse(t, `{b: [2, 1]}`)))
+	require.False(t, walky.EqualUnordered(parse(t, `[1, 2]`), parse(t, `["1", "2"]`)))
+}
+
+func TestEqualWithStyle(t *testing.T) {
+	base := parse(t, `
+	name: web # comment
+	script: |
+		echo hi
+	ports: [80, 443]
+	`)
+	reordered := parse(t, `
+	ports: [80, 443]
+	script: |
+		echo hi
+	name: web
+	`)
+	require.True(t, walky.EqualWithStyle(base, reordered))
+	require.True(t, walky.EqualWithStyle(base, base))
+
+	for _, doc := range []string{`
+	name: "web"
+	script: |
+		echo hi
+	ports: [80, 443]
+	`, `
+	name: web
+	script: "echo hi\n"
+	ports: [80, 443]
+	`, `
+	name: web
+	script: |
+		echo hi
+	ports:
+		- 80
+		- 443
+	`, `
+	name: web
+	script: |
+		echo hi
+	ports: {80: http}
+	`} {
+		other := parse(t, doc)
+		require.False(t, walky.EqualWithStyle(base, other), doc)
+		require.False(t, walky.EqualWithStyle(other, base), doc)
+	}
+	// Equal ignores style
+	require.True(t, walky.Equal(base, parse(t, `
+	name: 'web'
+	script: "echo hi\n"
+	ports:
+		- 80
+		- 443
+	`)))
+	require.False(t, walky.EqualWithStyle(base, nil))
+}
@@ -0,0 +1,188 @@
+package walky
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AsDuration will parse the scalar value of `node` with time.ParseDuration,
+// so values like `30s`, `5m` or `1h30m` are supported.  A YAMLError is
+// returned if the node is not a scalar or the value cannot be parsed.
+func AsDuration(node *yaml.Node) (time.Duration, error) {
+	node = Indirect(node)
+	if node.Kind != yaml.ScalarNode {
+		return 0, NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.ScalarNode), KindString(node.Kind)),
+			node,
+		)
+	}
+	d, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return 0, NewYAMLError(err, node)
+	}
+	return d, nil
+}
+
+// byteSuffixes are the multipliers for the suffixes supported by AsBytes,
+// ordered so the two character binary suffixes are checked first.
+var byteSuffixes = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"Pi", 1 << 50},
+	{"Ei", 1 << 60},
+	{"k", 1e3},
+	{"K", 1e3},
+	{"M", 1e6},
+	{"G", 1e9},
+	{"T", 1e12},
+	{"P", 1e15},
+	{"E", 1e18},
+}
+
+// AsBytes will parse the scalar value of `node` as an integer byte size with
+// an optional binary (`Ki`, `Mi`, `Gi`, `Ti`, `Pi`, `Ei`) or decimal (`k`,
+// `M`, `G`, `T`, `P`, `E`) suffix, so `10Mi` is 10485760 and `2G` is
+// 2000000000.  A YAMLError is returned if the node is not a scalar, the value
+// cannot be parsed or the result overflows an int64.
+func AsBytes(node *yaml.Node) (int64, error) {
+	node = Indirect(node)
+	if node.Kind != yaml.ScalarNode {
+		return 0, NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.ScalarNode), KindString(node.Kind)),
+			node,
+		)
+	}
+	value, multiplier := node.Value, int64(1)
+	for _, s := range byteSuffixes {
+		if strings.HasSuffix(value, s.suffix) {
+			value = strings.TrimSuffix(value, s.suffix)
+			multiplier = s.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, NewYAMLError(fmt.Errorf("invalid byte size %q", node.Value), node)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, NewYAMLError(fmt.Errorf("byte size %q overflows int64", node.Value), node)
+	}
+	return n * multiplier, nil
+}
+
+// BinaryValue will decode the base64 value of a `!!binary` tagged scalar
+// node, ignoring any whitespace in the value.  A YAMLError is returned if the
+// node is not tagged `!!binary` or the value is not valid base64.
+func BinaryValue(node *yaml.Node) ([]byte, error) {
+	node = Indirect(node)
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!binary" {
+		return nil, NewYAMLError(
+			fmt.Errorf("expected !!binary scalar, got %s %s", node.ShortTag(), KindString(node.Kind)),
+			node,
+		)
+	}
+	encoded := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, node.Value)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, NewYAMLError(err, node)
+	}
+	return data, nil
+}
+
+// ToGoValue will convert the node tree into native Go values:
+// map[string]interface{} for mappings, []interface{} for sequences, and
+// int64, float64, bool or nil for `!!int`, `!!float`, `!!bool` and `!!null`
+// scalars.  All other scalars (including `!!timestamp` and `!!binary`) are
+// returned as their string value.  Aliases and `!!merge` keys are resolved
+// first with the same precedence as RangeMap.  Mapping keys use the value of
+// the key scalar, so `1: one` has the key "1".  A YAMLError is returned if a
+// mapping key is not a scalar, if an `!!int` overflows an int64, or if a
+// recursive alias is found.
+func ToGoValue(node *yaml.Node) (interface{}, error) {
+	resolved, err := resolveNode(UnwrapDocument(node), map[*yaml.Node]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return toGoValue(resolved)
+}
+
+func toGoValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		// only empty documents remain after UnwrapDocument
+		return nil, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, NewYAMLError(
+					fmt.Errorf("expected node kind %q, got %q", KindString(yaml.ScalarNode), KindString(key.Kind)),
+					key,
+				)
+			}
+			value, err := toGoValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = value
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		s := make([]interface{}, 0, len(node.Content))
+		for _, elem := range node.Content {
+			value, err := toGoValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		return s, nil
+	case yaml.ScalarNode:
+		var err error
+		switch node.ShortTag() {
+		case "!!null":
+			return nil, nil
+		case "!!bool":
+			var b bool
+			err = node.Decode(&b)
+			if err == nil {
+				return b, nil
+			}
+		case "!!int":
+			var i int64
+			err = node.Decode(&i)
+			if err == nil {
+				return i, nil
+			}
+		case "!!float":
+			var f float64
+			err = node.Decode(&f)
+			if err == nil {
+				return f, nil
+			}
+		default:
+			return node.Value, nil
+		}
+		return nil, NewYAMLError(err, node)
+	}
+	// only recursive aliases remain after resolveNode
+	return nil, Errorf(node, "cannot convert recursive alias %q", node.Value)
+}
@@ -0,0 +1,182 @@
+package walky_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAsDuration(t *testing.T) {
+	doc := HereBytes(`
+	seconds: 30s
+	minutes: 5m
+	mixed: 1h30m
+	invalid: 10 parsecs
+	map: {}
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	for key, expected := range map[string]time.Duration{
+		"seconds": 30 * time.Second,
+		"minutes": 5 * time.Minute,
+		"mixed":   90 * time.Minute,
+	} {
+		got, err := walky.AsDuration(walky.GetKey(&root, key))
+		require.NoError(t, err)
+		require.Equal(t, expected, got)
+	}
+
+	_, err = walky.AsDuration(walky.GetKey(&root, "invalid"))
+	require.EqualError(t, err, `line 4:10 at "10 parsecs": time: unknown unit " parsecs" in duration "10 parsecs"`)
+
+	_, err = walky.AsDuration(walky.GetKey(&root, "map"))
+	require.EqualError(t, err, `line 5:6: expected node kind "scalar", got "mapping"`)
+}
+
+func TestAsBytes(t *testing.T) {
+	doc := HereBytes(`
+	plain: 1024
+	kibi: 2Ki
+	mebi: 10Mi
+	gibi: 2Gi
+	kilo: 3k
+	mega: 4M
+	giga: 5G
+	negative: -1Mi
+	fraction: 1.5Gi
+	unknown: 12Xi
+	overflow: 9Ei
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	for key, expected := range map[string]int64{
+		"plain": 1024,
+		"kibi":  2048,
+		"mebi":  10 * 1024 * 1024,
+		"gibi":  2 * 1024 * 1024 * 1024,
+		"kilo":  3000,
+		"mega":  4000000,
+		"giga":  5000000000,
+	} {
+		got, err := walky.AsBytes(walky.GetKey(&root, key))
+		require.NoError(t, err)
+		require.Equal(t, expected, got, key)
+	}
+
+	_, err = walky.AsBytes(walky.GetKey(&root, "negative"))
+	require.EqualError(t, err, `line 8:11 at "-1Mi": invalid byte size "-1Mi"`)
+	_, err = walky.AsBytes(walky.GetKey(&root, "fraction"))
+	require.EqualError(t, err, `line 9:11 at "1.5Gi": invalid byte size "1.5Gi"`)
+	_, err = walky.AsBytes(walky.GetKey(&root, "unknown"))
+	require.EqualError(t, err, `line 10:10 at "12Xi": invalid byte size "12Xi"`)
+	_, err = walky.AsBytes(walky.GetKey(&root, "overflow"))
+	require.EqualError(t, err, `line 11:11 at "9Ei": byte size "9Ei" overflows int64`)
+}
+
+func TestBinaryNode(t *testing.T) {
+	small := []byte("hello\x00world")
+	node := walky.NewBinaryNode(small)
+	require.Equal(t, "!!binary", node.Tag)
+	got, err := walky.BinaryValue(node)
+	require.NoError(t, err)
+	require.Equal(t, small, got)
+	out, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, "!!binary aGVsbG8Ad29ybGQ=\n", string(out))
+
+	large := make([]byte, 100)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	node = walky.NewBinaryNode(large)
+	require.Equal(t, yaml.LiteralStyle, node.Style)
+	got, err = walky.BinaryValue(node)
+	require.NoError(t, err)
+	require.Equal(t, large, got)
+
+	// round trip through a document
+	m := walky.NewMappingNode()
+	err = walky.AssignMapNode(m, walky.NewStringNode("data"), node)
+	require.NoError(t, err)
+	out, err = yaml.Marshal(m)
+	require.NoError(t, err)
+	var decoded struct {
+		Data string `yaml:"data"`
+	}
+	err = yaml.Unmarshal(out, &decoded)
+	require.NoError(t, err)
+	require.Equal(t, string(large), decoded.Data)
+	var root yaml.Node
+	err = yaml.Unmarshal(out, &root)
+	require.NoError(t, err)
+	got, err = walky.BinaryValue(walky.GetKey(&root, "data"))
+	require.NoError(t, err)
+	require.Equal(t, large, got)
+
+	_, err = walky.BinaryValue(walky.NewStringNode("aGVsbG8="))
+	require.EqualError(t, err, ` at "aGVsbG8=": expected !!binary scalar, got !!str scalar`)
+	_, err = walky.BinaryValue(parse(t, `!!binary "not base64!"`))
+	require.EqualError(t, err, `line 1:1 at "not base64!": illegal base64 data at input byte 9`)
+}
+
+func TestToGoValue(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+		replicas: 1
+	service:
+		<<: *defaults
+		replicas: 3
+		ratio: 0.5
+		enabled: true
+		version: "1.0"
+		missing: ~
+		ports: [80, 443]
+		1: one
+		created: 2023-01-02T03:04:05Z
+	`)
+	got, err := walky.ToGoValue(root)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"defaults": map[string]interface{}{
+			"image":    "web:1.0",
+			"replicas": int64(1),
+		},
+		"service": map[string]interface{}{
+			"image":    "web:1.0",
+			"replicas": int64(3),
+			"ratio":    0.5,
+			"enabled":  true,
+			"version":  "1.0",
+			"missing":  nil,
+			"ports":    []interface{}{int64(80), int64(443)},
+			"1":        "one",
+			"created":  "2023-01-02T03:04:05Z",
+		},
+	}, got)
+
+	got, err = walky.ToGoValue(parse(t, `[a, 1, 1.5, false, ~]`))
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{"a", int64(1), 1.5, false, nil}, got)
+
+	got, err = walky.ToGoValue(&yaml.Node{Kind: yaml.DocumentNode})
+	require.NoError(t, err)
+	require.Nil(t, got)
+
+	_, err = walky.ToGoValue(parse(t, `big: 18446744073709551615`))
+	require.Error(t, err)
+	require.IsType(t, walky.YAMLError{}, err)
+
+	_, err = walky.ToGoValue(parse(t, `
+	? [a, b]
+	: value
+	`))
+	require.EqualError(t, err, `line 1:3: expected node kind "scalar", got "sequence"`)
+}
@@ -0,0 +1,404 @@
+package walky
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SeqOpKind is the type of operation in a SeqOp.
+type SeqOpKind int
+
+const (
+	// SeqKeep indicates the element is in both sequences.
+	SeqKeep SeqOpKind = iota
+	// SeqInsert indicates the element is only in the second sequence.
+	SeqInsert
+	// SeqDelete indicates the element is only in the first sequence.
+	SeqDelete
+)
+
+func (k SeqOpKind) String() string {
+	switch k {
+	case SeqKeep:
+		return "Keep"
+	case SeqInsert:
+		return "Insert"
+	case SeqDelete:
+		return "Delete"
+	default:
+		return "Invalid"
+	}
+}
+
+// SeqOp is a single operation from AlignSequences.
+type SeqOp struct {
+	Kind SeqOpKind
+	// AIndex is the index of the element in the first sequence, or -1 for
+	// SeqInsert.
+	AIndex int
+	// BIndex is the index of the element in the second sequence, or -1 for
+	// SeqDelete.
+	BIndex int
+	// Node is the element from the first sequence for SeqKeep and SeqDelete
+	// and the element from the second sequence for SeqInsert.
+	Node *yaml.Node
+}
+
+// AlignSequences will compute the longest common subsequence of the elements
+// of sequences `a` and `b` (comparing elements with Equal) and return the
+// operations to transform `a` into `b`.  Every element of both sequences is
+// accounted for in order, so a single element inserted into the middle of `b`
+// results in SeqKeep operations for all elements other than a single SeqInsert.
+// Aliases are resolved and a node that is not a sequence is treated as an
+// empty sequence.
+func AlignSequences(a, b *yaml.Node) []SeqOp {
+	aContent, bContent := seqContent(a), seqContent(b)
+	n, m := len(aContent), len(bContent)
+	// lcs[i][j] is the length of the longest common subsequence of
+	// aContent[i:] and bContent[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case Equal(aContent[i], bContent[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ops := make([]SeqOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case Equal(aContent[i], bContent[j]):
+			ops = append(ops, SeqOp{Kind: SeqKeep, AIndex: i, BIndex: j, Node: aContent[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, SeqOp{Kind: SeqDelete, AIndex: i, BIndex: -1, Node: aContent[i]})
+			i++
+		default:
+			ops = append(ops, SeqOp{Kind: SeqInsert, AIndex: -1, BIndex: j, Node: bContent[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, SeqOp{Kind: SeqDelete, AIndex: i, BIndex: -1, Node: aContent[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, SeqOp{Kind: SeqInsert, AIndex: -1, BIndex: j, Node: bContent[j]})
+	}
+	return ops
+}
+
+// seqContent returns the elements of the sequence `node`, or nil if the
+// node is not a sequence.
+func seqContent(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	node = Indirect(node)
+	if node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// ChangeKind is the type of difference reported in a Change.
+type ChangeKind int
+
+const (
+	// ChangeAdded indicates the node is only in the second document.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved indicates the node is only in the first document.
+	ChangeRemoved
+	// ChangeModified indicates the node differs between the documents.
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "Added"
+	case ChangeRemoved:
+		return "Removed"
+	case ChangeModified:
+		return "Modified"
+	default:
+		return "Invalid"
+	}
+}
+
+// Change is a single difference found by Diff.  The Path elements are
+// suitable for WalkPath, keys are strings except for aliases and complex keys
+// which are the resolved key *yaml.Node.  Before is nil for added nodes and After is nil for
+// removed nodes.
+type Change struct {
+	Path   []interface{}
+	Kind   ChangeKind
+	Before *yaml.Node
+	After  *yaml.Node
+}
+
+// String renders the change on a single line, with the path rendered by
+// PathString and the values in flow style, for example:
+//
+//	spec.replicas: 3 -> 5
+//	spec.ports[2]: added 8443
+//	spec.debug: removed true
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("%s: added %s", PathString(c.Path...), flowString(c.After))
+	case ChangeRemoved:
+		return fmt.Sprintf("%s: removed %s", PathString(c.Path...), flowString(c.Before))
+	default:
+		return fmt.Sprintf("%s: %s -> %s", PathString(c.Path...), flowString(c.Before), flowString(c.After))
+	}
+}
+
+// Diff returns the structural differences between `a` and `b`, in document
+// order.  Mappings (including merged keys) are compared by key and sequences
+// by index, so an element inserted into the middle of a sequence is reported
+// as a modification of each following element and an addition at the end
+// (see AlignSequences to align sequence elements instead).  Aliases are
+// resolved and leaf nodes are compared with Equal, so comments and styles are
+// not differences.  Nodes of different kinds are reported as modified.
+func Diff(a, b *yaml.Node) []Change {
+	return diff(a, b, nil)
+}
+
+// diff implements Diff, with `path` as the path to `a` and `b`.
+func diff(a, b *yaml.Node, path []interface{}) []Change {
+	a, b = Indirect(a), Indirect(b)
+	child := func(elem interface{}) []interface{} {
+		return append(path[:len(path):len(path)], elem)
+	}
+	// aliases and complex keys cannot be matched by their Value, so use
+	// the resolved key node for the path, which NodeMatcher will match.
+	keyPath := func(key *yaml.Node) []interface{} {
+		if key.Kind == yaml.ScalarNode {
+			return child(key.Value)
+		}
+		return child(Indirect(key))
+	}
+	changes := []Change{}
+	switch {
+	case a.Kind == yaml.MappingNode && b.Kind == yaml.MappingNode:
+		aPairs, bPairs := mapPairs(a), mapPairs(b)
+		matched := make([]bool, len(bPairs))
+		for i := 0; i < len(aPairs); i += 2 {
+			found := false
+			for j := 0; j < len(bPairs); j += 2 {
+				if matched[j] || !Equal(aPairs[i], bPairs[j]) {
+					continue
+				}
+				matched[j], found = true, true
+				changes = append(changes, diff(aPairs[i+1], bPairs[j+1], keyPath(aPairs[i]))...)
+				break
+			}
+			if !found {
+				changes = append(changes, Change{Path: keyPath(aPairs[i]), Kind: ChangeRemoved, Before: aPairs[i+1]})
+			}
+		}
+		for j := 0; j < len(bPairs); j += 2 {
+			if !matched[j] {
+				changes = append(changes, Change{Path: keyPath(bPairs[j]), Kind: ChangeAdded, After: bPairs[j+1]})
+			}
+		}
+	case a.Kind == yaml.SequenceNode && b.Kind == yaml.SequenceNode:
+		for i := 0; i < len(a.Content) || i < len(b.Content); i++ {
+			switch {
+			case i >= len(b.Content):
+				changes = append(changes, Change{Path: child(i), Kind: ChangeRemoved, Before: a.Content[i]})
+			case i >= len(a.Content):
+				changes = append(changes, Change{Path: child(i), Kind: ChangeAdded, After: b.Content[i]})
+			default:
+				changes = append(changes, diff(a.Content[i], b.Content[i], child(i))...)
+			}
+		}
+	case !Equal(a, b):
+		changes = append(changes, Change{Path: path, Kind: ChangeModified, Before: a, After: b})
+	}
+	return changes
+}
+
+// AnnotateChanges returns a copy of `new` with comments describing how it
+// differs from `old`.  Modified values get a line comment like
+// `# changed from 8080` and added map entries and sequence elements get an
+// `# added` comment.  Comments cannot be placed inside flow collections, so
+// any change within a flow collection is reported on the outermost flow
+// collection as `# changed from [a, b]`.  Existing comments are preserved,
+// with the annotation appended to any existing line comment.  Other removed
+// nodes are not annotated since they are not present in the copy.
+func AnnotateChanges(old, new *yaml.Node) *yaml.Node {
+	annotated := CopyNode(new)
+	root := UnwrapDocument(annotated)
+	done := map[*yaml.Node]bool{}
+	for _, c := range Diff(old, annotated) {
+		lineage := pathLineage(root, c.Path)
+		flowAt := -1
+		for k := 0; k < len(lineage) && k < len(c.Path); k++ {
+			if lineage[k].Style&yaml.FlowStyle != 0 {
+				flowAt = k
+				break
+			}
+		}
+		switch {
+		case flowAt >= 0:
+			target := lineage[flowAt]
+			if done[target] {
+				continue
+			}
+			done[target] = true
+			before := pathLineage(UnwrapDocument(old), c.Path[:flowAt])
+			var parent *yaml.Node
+			if flowAt > 0 {
+				parent = lineage[flowAt-1]
+			}
+			annotateNode(parent, target, "changed from "+flowString(before[len(before)-1]))
+		case c.Kind == ChangeRemoved:
+			continue
+		default:
+			var parent *yaml.Node
+			if len(lineage) == len(c.Path)+1 && len(lineage) > 1 {
+				parent = lineage[len(lineage)-2]
+			}
+			comment := "added"
+			if c.Kind == ChangeModified {
+				comment = "changed from " + flowString(c.Before)
+			}
+			annotateNode(parent, c.After, comment)
+		}
+	}
+	return annotated
+}
+
+// pathLineage returns the nodes found by following each element of `path`
+// from `root`, starting with `root`.  If an element is not found the nodes up
+// to that point are returned.
+func pathLineage(root *yaml.Node, path []interface{}) []*yaml.Node {
+	lineage := []*yaml.Node{root}
+	for _, elem := range path {
+		var next *yaml.Node
+		// errors are not possible since our NodeFunc never returns one
+		// and the path elements came from diff.
+		_ = WalkPath(lineage[len(lineage)-1], func(n *yaml.Node) error {
+			if next == nil {
+				next = n
+			}
+			return nil
+		}, elem)
+		if next == nil {
+			break
+		}
+		lineage = append(lineage, next)
+	}
+	return lineage
+}
+
+// annotateNode adds `comment` to `node` where yaml.v3 will emit it, block
+// collections in mappings are annotated on the key and block collections in
+// sequences get a head comment.  `parent` may be nil if it is not known.
+func annotateNode(parent, node *yaml.Node, comment string) {
+	target := node
+	if parent != nil && (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && node.Style&yaml.FlowStyle == 0 {
+		if parent.Kind == yaml.SequenceNode {
+			node.HeadComment = joinComments(node.HeadComment, "# "+comment)
+			return
+		}
+		for i := 1; i < len(parent.Content); i += 2 {
+			if parent.Content[i] == node {
+				target = parent.Content[i-1]
+			}
+		}
+	}
+	if target.LineComment != "" {
+		target.LineComment += " # " + comment
+		return
+	}
+	target.LineComment = "# " + comment
+}
+
+// flowString returns a single line representation of `node` for use in
+// messages.
+func flowString(node *yaml.Node) string {
+	node = CopyNode(Indirect(node))
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(node, allNodesWalker(func(n *yaml.Node) error {
+		n.HeadComment, n.LineComment, n.FootComment = "", "", ""
+		if n.Kind == yaml.MappingNode || n.Kind == yaml.SequenceNode {
+			n.Style |= yaml.FlowStyle
+		}
+		return nil
+	}))
+	if IsNull(node) {
+		return "null"
+	}
+	out, err := yaml.Marshal(node)
+	if err != nil {
+		return node.Value
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// EditScript returns human-readable operations that transform `a` into `b`,
+// for example:
+//
+//	set servers[0].port = 8080
+//	delete legacy.flag
+//
+// Paths are rendered with PathString and values are rendered on a single
+// line in flow style.  Added and modified nodes are both reported with `set`.
+// Operations are listed in document order, except that sequence elements
+// removed from the end of a sequence are deleted from the last index first so
+// that the script can be applied in order.
+func EditScript(a, b *yaml.Node) []string {
+	changes := Diff(a, b)
+	script := make([]string, 0, len(changes))
+	for i := 0; i < len(changes); i++ {
+		c := changes[i]
+		if c.Kind != ChangeRemoved {
+			script = append(script, fmt.Sprintf("set %s = %s", PathString(c.Path...), flowString(c.After)))
+			continue
+		}
+		// find the run of removed elements from the same sequence
+		// and delete them in reverse order.
+		end := i + 1
+		if _, ok := c.Path[len(c.Path)-1].(int); ok {
+			for end < len(changes) && changes[end].Kind == ChangeRemoved && sameParent(c.Path, changes[end].Path) {
+				end++
+			}
+		}
+		for j := end - 1; j >= i; j-- {
+			script = append(script, "delete "+PathString(changes[j].Path...))
+		}
+		i = end - 1
+	}
+	return script
+}
+
+// sameParent returns true if the paths `a` and `b` differ only by the last
+// element, and that element is a sequence index in both.
+func sameParent(a, b []interface{}) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	if _, ok := b[len(b)-1].(int); !ok {
+		return false
+	}
+	for i := 0; i < len(a)-1; i++ {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,255 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAlignSequences(t *testing.T) {
+	type op struct {
+		Kind   walky.SeqOpKind
+		AIndex int
+		BIndex int
+		Value  string
+	}
+	align := func(a, b string) []op {
+		ops := []op{}
+		for _, o := range walky.AlignSequences(parse(t, a), parse(t, b)) {
+			ops = append(ops, op{o.Kind, o.AIndex, o.BIndex, o.Node.Value})
+		}
+		return ops
+	}
+
+	require.Equal(t, []op{
+		{walky.SeqKeep, 0, 0, "a"},
+		{walky.SeqKeep, 1, 1, "b"},
+		{walky.SeqInsert, -1, 2, "new"},
+		{walky.SeqKeep, 2, 3, "c"},
+		{walky.SeqKeep, 3, 4, "d"},
+	}, align(`[a, b, c, d]`, `[a, b, new, c, d]`))
+
+	require.Equal(t, []op{
+		{walky.SeqKeep, 0, 0, "a"},
+		{walky.SeqDelete, 1, -1, "b"},
+		{walky.SeqKeep, 2, 1, "c"},
+		{walky.SeqDelete, 3, -1, "d"},
+		{walky.SeqInsert, -1, 2, "e"},
+	}, align(`[a, b, c, d]`, `[a, c, e]`))
+
+	require.Equal(t, []op{
+		{walky.SeqInsert, -1, 0, "a"},
+	}, align(`~`, `[a]`))
+
+	ops := walky.AlignSequences(
+		parse(t, `[{name: a, port: 80}, {name: b}]`),
+		parse(t, `[{port: 80, name: a}, {name: c}, {name: b}]`),
+	)
+	kinds := []walky.SeqOpKind{}
+	for _, o := range ops {
+		kinds = append(kinds, o.Kind)
+	}
+	require.Equal(t, []walky.SeqOpKind{walky.SeqKeep, walky.SeqInsert, walky.SeqKeep}, kinds)
+	require.Equal(t, "Insert", ops[1].Kind.String())
+}
+
+func TestAnnotateChanges(t *testing.T) {
+	old := parse(t, `
+	name: web
+	port: 8080 # http
+	replicas: 3
+	tags: [a, b]
+	env:
+		- name: A
+		  value: "1"
+	flow: {a: 1, b: 2}
+	legacy: true
+	`)
+	new := parse(t, `
+	name: web
+	port: 9090 # http
+	replicas: 3
+	tags: [a, c, d]
+	env:
+		- name: A
+		  value: "2"
+		- name: B
+		  value: "3"
+	flow: {a: 1}
+	limits:
+		cpu: 2
+	debug: false
+	`)
+	got, err := yaml.Marshal(walky.AnnotateChanges(old, new))
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		port: 9090 # http # changed from 8080
+		replicas: 3
+		tags: [a, c, d] # changed from [a, b]
+		env:
+			- name: A
+			  value: "2" # changed from "1"
+			# added
+			- name: B
+			  value: "3"
+		flow: {a: 1} # changed from {a: 1, b: 2}
+		limits: # added
+			cpu: 2
+		debug: false # added
+	`), string(got))
+
+	// new is not modified
+	got, err = yaml.Marshal(new)
+	require.NoError(t, err)
+	require.NotContains(t, string(got), "changed")
+}
+
+func TestEditScript(t *testing.T) {
+	a := parse(t, `
+	name: web
+	servers:
+		- host: web1
+		  port: 80
+	tags: [a, b, c, d]
+	legacy:
+		flag: true
+		keep: 1
+	`)
+	b := parse(t, `
+	name: web # comments are ignored
+	servers:
+		- host: web1
+		  port: 8080
+		- host: web2
+		  port: 80
+	tags: [a, x]
+	legacy:
+		keep: 1
+	limits: {cpu: 2}
+	`)
+	script := walky.EditScript(a, b)
+	require.Equal(t, []string{
+		"set servers[0].port = 8080",
+		"set servers[1] = {host: web2, port: 80}",
+		"set tags[1] = x",
+		"delete tags[3]",
+		"delete tags[2]",
+		"delete legacy.flag",
+		"set limits = {cpu: 2}",
+	}, script)
+
+	require.Empty(t, walky.EditScript(a, a))
+}
+
+func TestDiff(t *testing.T) {
+	a := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+	spec:
+		<<: *defaults
+		replicas: 3 # three
+		ports: [80, 443]
+		debug: true
+		labels: {app: web}
+	`)
+	b := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+	spec:
+		<<: *defaults
+		replicas: 5
+		ports: [80, 443, 8443]
+		labels:
+			app: web
+			tier: frontend
+	`)
+	changes := walky.Diff(a, b)
+	got := []string{}
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	require.Equal(t, []string{
+		"spec.replicas: 3 -> 5",
+		"spec.ports[2]: added 8443",
+		"spec.debug: removed true",
+		"spec.labels.tier: added frontend",
+	}, got)
+
+	require.Equal(t, []interface{}{"spec", "replicas"}, changes[0].Path)
+	require.Equal(t, walky.ChangeModified, changes[0].Kind)
+	require.Equal(t, "3", changes[0].Before.Value)
+	require.Equal(t, "5", changes[0].After.Value)
+	require.Equal(t, walky.ChangeAdded, changes[1].Kind)
+	require.Nil(t, changes[1].Before)
+	require.Equal(t, walky.ChangeRemoved, changes[2].Kind)
+	require.Nil(t, changes[2].After)
+	require.Equal(t, "Removed", changes[2].Kind.String())
+
+	// aliased keys use the anchored value in the path
+	changes = walky.Diff(parse(t, `
+	key: &k name
+	settings:
+		*k : web
+		other: 1
+	`), parse(t, `
+	key: name
+	settings:
+		name: api
+	`))
+	got = []string{}
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	require.Equal(t, []string{
+		"settings.name: web -> api",
+		"settings.other: removed 1",
+	}, got)
+	require.Len(t, changes[0].Path, 2)
+	require.Equal(t, "name", changes[0].Path[1].(*yaml.Node).Value)
+
+	// complex key paths can be walked
+	ca := parse(t, `
+	? [x, y]
+	: 1
+	`)
+	cb := parse(t, `
+	? [x, y]
+	: 2
+	`)
+	changes = walky.Diff(ca, cb)
+	require.Len(t, changes, 1)
+	require.Equal(t, `["[x, y]"]: 1 -> 2`, changes[0].String())
+	for doc, want := range map[*yaml.Node]string{ca: "1", cb: "2"} {
+		found := []string{}
+		err := walky.WalkPath(doc, func(node *yaml.Node) error {
+			found = append(found, node.Value)
+			return nil
+		}, changes[0].Path...)
+		require.NoError(t, err)
+		require.Equal(t, []string{want}, found)
+	}
+
+	// comments and styles are not differences
+	require.Empty(t, walky.Diff(a, parse(t, `
+	defaults: {image: "web:1.0"}
+	spec:
+		image: web:1.0 # merged
+		replicas: 3
+		ports:
+			- 80
+			- 443
+		debug: true
+		labels: {app: web}
+	`)))
+
+	// kind changes are modifications
+	require.Equal(t, []walky.Change{{
+		Path:   []interface{}{"a"},
+		Kind:   walky.ChangeModified,
+		Before: walky.GetKey(parse(t, `a: [1]`), "a"),
+		After:  walky.GetKey(parse(t, `a: {b: 1}`), "a"),
+	}}, walky.Diff(parse(t, `a: [1]`), parse(t, `a: {b: 1}`)))
+}
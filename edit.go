@@ -0,0 +1,290 @@
+package walky
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PreviewSet will report the change that assigning `value` to the node at
+// `path` would make, without modifying `root`.  The `before` node is a copy
+// of the current node at `path` and the `after` node is the node as it would
+// be after the update (comments from the current node are preserved, the same
+// as AssignNode).  If the path matches multiple nodes only the first is
+// previewed.  An error is returned if `value` cannot be converted with ToNode
+// or if `path` does not match any node.
+func PreviewSet(root *yaml.Node, value interface{}, path ...interface{}) (before, after *yaml.Node, err error) {
+	update, err := ToNode(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = WalkPath(CopyNode(root), func(node *yaml.Node) error {
+		if after != nil {
+			return nil
+		}
+		before = CopyNode(node)
+		AssignNode(node, CopyNode(update))
+		after = node
+		return nil
+	}, path...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after == nil {
+		return nil, nil, fmt.Errorf("path %v not found", path)
+	}
+	return before, after, nil
+}
+
+// Tx collects a batch of edits to be applied to a node tree together with
+// Commit.  The operations are applied to the tree in place, so pointers to
+// nodes within the tree remain valid.  Sets are applied in order, so a later
+// operation can refer to a key added by an earlier Set.  Deletes are applied
+// after every other operation, so they do not shift the targets of later
+// ones, for example deleting index 0 and then setting index 1 of a sequence
+// will update the element that was originally at index 1.
+type Tx struct {
+	root *yaml.Node
+	ops  []txOp
+}
+
+type txOp struct {
+	delete bool
+	value  interface{}
+	path   []interface{}
+}
+
+// Begin starts a new transaction for edits to `root`.
+func Begin(root *yaml.Node) *Tx {
+	return &Tx{root: root}
+}
+
+// Set queues an update to assign `value` to every node matching `path`, with
+// the same semantics as AssignNode.  If `path` does not match any node but the
+// parent path does match and the last path element is a string, the key will
+// be added to the matching mappings with AssignMapNode.
+func (tx *Tx) Set(value interface{}, path ...interface{}) {
+	tx.ops = append(tx.ops, txOp{value: value, path: path})
+}
+
+// Delete queues the removal of every node matching `path` from its parent
+// mapping or sequence.
+func (tx *Tx) Delete(path ...interface{}) {
+	tx.ops = append(tx.ops, txOp{delete: true, path: path})
+}
+
+// Commit applies all of the queued operations to the root node.  Each node is
+// saved before it is modified, and if any operation fails the saved nodes are
+// restored, so if an error is returned the tree is left unchanged.  The
+// queued operations are cleared after Commit.
+func (tx *Tx) Commit() error {
+	ops := tx.ops
+	tx.ops = nil
+	undo := []func(){}
+	save := func(node *yaml.Node) {
+		saved := *node
+		saved.Content = append([]*yaml.Node(nil), node.Content...)
+		undo = append(undo, func() {
+			*node = saved
+		})
+	}
+	rollback := func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+	deletes := make([]func(), 0, len(ops))
+	for _, op := range ops {
+		if op.delete {
+			f, err := txDelete(tx.root, op.path)
+			if err != nil {
+				rollback()
+				return err
+			}
+			deletes = append(deletes, f)
+			continue
+		}
+		if err := txSet(tx.root, op.value, op.path, save); err != nil {
+			rollback()
+			return err
+		}
+	}
+	// the deleted nodes were resolved before any were removed, so index
+	// shifts from earlier deletes do not affect later ones.  Removing
+	// a resolved node cannot fail, so no rollback is needed here.
+	for _, f := range deletes {
+		f()
+	}
+	return nil
+}
+
+// txParents returns the parent nodes and the matching child nodes of `path`
+// in `root`.
+func txParents(root *yaml.Node, path []interface{}) (parents, children []*yaml.Node, err error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("empty path")
+	}
+	last := path[len(path)-1]
+	err = WalkPath(root, func(parent *yaml.Node) error {
+		return WalkPath(parent, func(child *yaml.Node) error {
+			parents = append(parents, parent)
+			children = append(children, child)
+			return nil
+		}, last)
+	}, path[:len(path)-1]...)
+	return parents, children, err
+}
+
+// txSet assigns `value` to the nodes at `path` in `root`, calling `save` with
+// each node before it is modified.
+func txSet(root *yaml.Node, value interface{}, path []interface{}, save func(*yaml.Node)) error {
+	update, err := ToNode(value)
+	if err != nil {
+		return err
+	}
+	targets := []*yaml.Node{}
+	err = WalkPath(root, func(node *yaml.Node) error {
+		targets = append(targets, node)
+		return nil
+	}, path...)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 {
+		for _, target := range targets {
+			save(target)
+			AssignNode(target, CopyNode(update))
+		}
+		return nil
+	}
+	// an empty path always matches the root, so we have at least
+	// one path element here.
+	key, ok := path[len(path)-1].(string)
+	if !ok {
+		return fmt.Errorf("path %v not found", path)
+	}
+	parents := []*yaml.Node{}
+	err = WalkPath(root, func(node *yaml.Node) error {
+		parents = append(parents, node)
+		return nil
+	}, path[:len(path)-1]...)
+	if err != nil {
+		return err
+	}
+	if len(parents) == 0 {
+		return fmt.Errorf("path %v not found", path)
+	}
+	for _, parent := range parents {
+		save(parent)
+		err := AssignMapNode(parent, NewStringNode(key), CopyNode(update))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txDelete resolves the nodes at `path` in `root` and returns a function to
+// remove them.
+func txDelete(root *yaml.Node, path []interface{}) (func(), error) {
+	parents, children, err := txParents(root, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("path %v not found", path)
+	}
+	return func() {
+		for i, parent := range parents {
+			// remove by identity rather than with Remove, which
+			// would remove the first Equal sequence element.
+			step, offset := 1, 0
+			if parent.Kind == yaml.MappingNode {
+				step, offset = 2, 1
+			}
+			for j := offset; j < len(parent.Content); j += step {
+				if parent.Content[j] == children[i] {
+					parent.Content = append(parent.Content[:j-offset], parent.Content[j-offset+step:]...)
+					break
+				}
+			}
+		}
+	}, nil
+}
+
+// SetPath will assign `value` to the node at `path` in `root`, creating any
+// missing nodes along the way, so `SetPath(root, v, "a", "b", "c")` will
+// create `a: {b: {c: v}}` if none of the keys exist.  The path is walked
+// with WalkPath and WithCreateMissing, so path elements are strings or
+// *yaml.Node for mapping keys and ints for sequence indexes, and sequences
+// are padded with `!!null` elements as needed to reach an index.  The node
+// at `path` is updated with AssignNode using a copy of `value`, so comments
+// on existing nodes are preserved and `value` is never shared with `root`.  An error is returned if a node along the path is not a
+// mapping or sequence as required by the path element.
+func SetPath(root *yaml.Node, value *yaml.Node, path ...interface{}) error {
+	if root.Kind == yaml.DocumentNode && len(root.Content) == 0 {
+		root.Content = append(root.Content, NewNullNode())
+	}
+	return WalkPath(root, func(node *yaml.Node) error {
+		AssignNode(node, CopyNode(value))
+		return nil
+	}, append(path[:len(path):len(path)], WithCreateMissing())...)
+}
+
+// DeletePath will remove the node at `path` from its parent mapping or
+// sequence in `root`, returning true if a node was removed.  The last path
+// element is a string or *yaml.Node to remove a key (and its value) from a
+// mapping with Remove, or an int to remove an element from a sequence
+// (negative indexes count back from the end, the same as IndexMatcher).  If
+// any part of the path does not exist false is returned without an error.  If
+// the parent path matches multiple nodes only the first is modified.
+func DeletePath(root *yaml.Node, path ...interface{}) (bool, error) {
+	if len(path) == 0 {
+		return false, fmt.Errorf("empty path")
+	}
+	var parent *yaml.Node
+	err := WalkPath(root, func(node *yaml.Node) error {
+		if parent == nil {
+			parent = node
+		}
+		return nil
+	}, path[:len(path)-1]...)
+	if err != nil || parent == nil {
+		return false, err
+	}
+	switch last := path[len(path)-1].(type) {
+	case string, *yaml.Node:
+		if parent.Kind != yaml.MappingNode {
+			return false, nil
+		}
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			key := parent.Content[i]
+			matched := false
+			if k, ok := last.(string); ok {
+				matched = key.Value == k
+			} else {
+				matched = Equal(key, last.(*yaml.Node))
+			}
+			if matched {
+				return Remove(parent, key), nil
+			}
+		}
+		return false, nil
+	case int:
+		if parent.Kind != yaml.SequenceNode {
+			return false, nil
+		}
+		if last < 0 {
+			last += len(parent.Content)
+		}
+		if last < 0 || last >= len(parent.Content) {
+			return false, nil
+		}
+		// remove by position rather than Remove, which would remove the
+		// first element Equal to the target
+		parent.Content = append(parent.Content[:last], parent.Content[last+1:]...)
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid path element type %T (%v)", last, last)
+	}
+}
@@ -0,0 +1,241 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPreviewSet(t *testing.T) {
+	doc := Here(`
+	servers:
+		- host: web1
+		  port: 80 # http
+		- host: web2
+		  port: 8080
+	`)
+	root := parse(t, doc)
+
+	before, after, err := walky.PreviewSet(root, 443, "servers", 0, "port")
+	require.NoError(t, err)
+	require.Equal(t, "80", before.Value)
+	require.Equal(t, "443", after.Value)
+	require.Equal(t, "# http", after.LineComment)
+
+	before, after, err = walky.PreviewSet(root, map[string]int{"a": 1}, "servers", 1)
+	require.NoError(t, err)
+	require.Equal(t, "web2", walky.GetKey(before, "host").Value)
+	require.Nil(t, walky.GetKey(after, "host"))
+	require.Equal(t, "1", walky.GetKey(after, "a").Value)
+
+	// original is unchanged
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, doc, string(got))
+
+	_, _, err = walky.PreviewSet(root, 1, "servers", 5)
+	require.EqualError(t, err, "path [servers 5] not found")
+}
+
+func TestTx(t *testing.T) {
+	doc := Here(`
+	name: web # the name
+	servers:
+		- host: web1
+		  port: 80
+		- host: web2
+		  port: 80
+		- host: web3
+		  port: 80
+	tags: [a, b, a]
+	`)
+	root := parse(t, doc)
+
+	servers := walky.GetKey(root, "servers")
+	tx := walky.Begin(root)
+	tx.Delete("servers", 0)
+	// deletes are applied last, so index 1 is still web2
+	tx.Set(8080, "servers", 1, "port")
+	tx.Set("api", "name")
+	tx.Set(true, "enabled")
+	tx.Set(map[string]string{"app": "web"}, "labels")
+	// keys added by an earlier Set can be updated
+	tx.Set("prod", "labels", "env")
+	tx.Delete("tags", 2)
+	err := tx.Commit()
+	require.NoError(t, err)
+	// the tree is updated in place
+	require.Same(t, servers, walky.GetKey(root, "servers"))
+	require.Len(t, servers.Content, 2)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		enabled: true
+		labels:
+			app: web
+			env: prod
+		name: api # the name
+		servers:
+			- host: web2
+			  port: 8080
+			- host: web3
+			  port: 80
+		tags: [a, b]
+	`), string(got))
+
+	t.Run("rollback", func(t *testing.T) {
+		root := parse(t, doc)
+		servers := walky.GetKey(root, "servers")
+		web1 := servers.Content[0]
+		tx := walky.Begin(root)
+		tx.Set("api", "name")
+		tx.Set(443, "servers", 0, "port")
+		tx.Set("x", "servers", 0, "extra")
+		tx.Delete("servers", 0)
+		tx.Delete("servers", 5)
+		err := tx.Commit()
+		require.EqualError(t, err, "path [servers 5] not found")
+
+		got, err := yaml.Marshal(root)
+		require.NoError(t, err)
+		require.Equal(t, doc, string(got))
+		require.Same(t, servers, walky.GetKey(root, "servers"))
+		require.Same(t, web1, servers.Content[0])
+
+		tx.Set("api", "missing", "name")
+		err = tx.Commit()
+		require.EqualError(t, err, "path [missing name] not found")
+	})
+}
+
+func TestSetPath(t *testing.T) {
+	root := parse(t, `
+	# the name
+	name: web # line comment
+	spec:
+		ports: [80]
+	empty:
+	`)
+	err := walky.SetPath(root, walky.NewStringNode("api"), "name")
+	require.NoError(t, err)
+	err = walky.SetPath(root, walky.NewIntNode(443), "spec", "ports", 1)
+	require.NoError(t, err)
+	err = walky.SetPath(root, walky.NewStringNode("v"), "a", "b", "c")
+	require.NoError(t, err)
+	err = walky.SetPath(root, walky.NewStringNode("x"), "empty", "list", 2, "key")
+	require.NoError(t, err)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		a:
+			b:
+				c: v
+		# the name
+		name: api # line comment
+		spec:
+			ports: [80, 443]
+		empty:
+			list:
+				-
+				-
+				- key: x
+	`), string(got))
+
+	// existing nodes are updated in place
+	ports := walky.GetKey(walky.GetKey(root, "spec"), "ports")
+	err = walky.SetPath(root, walky.NewIntNode(8080), "spec", "ports", 0)
+	require.NoError(t, err)
+	require.Same(t, ports, walky.GetKey(walky.GetKey(root, "spec"), "ports"))
+	require.Equal(t, "8080", ports.Content[0].Value)
+
+	// the value is copied, so it can be reused
+	labels := walky.NewMappingNode()
+	err = walky.AssignMapNode(labels, walky.NewStringNode("app"), walky.NewStringNode("web"))
+	require.NoError(t, err)
+	err = walky.SetPath(root, labels, "spec", "labels")
+	require.NoError(t, err)
+	err = walky.SetPath(root, labels, "spec", "selector")
+	require.NoError(t, err)
+	walky.GetKey(walky.GetKey(walky.GetKey(root, "spec"), "labels"), "app").Value = "api"
+	require.Equal(t, "web", walky.GetKey(walky.GetKey(walky.GetKey(root, "spec"), "selector"), "app").Value)
+	require.Equal(t, "web", walky.GetKey(labels, "app").Value)
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+	err = walky.SetPath(doc, walky.NewBoolNode(true), "enabled")
+	require.NoError(t, err)
+	got, err = yaml.Marshal(doc)
+	require.NoError(t, err)
+	require.Equal(t, "enabled: true\n", string(got))
+
+	err = walky.SetPath(root, walky.NewStringNode("x"), "name", "nested")
+	require.EqualError(t, err, `line 2:7 at "api": expected node kind "mapping", got "scalar"`)
+	err = walky.SetPath(root, walky.NewStringNode("x"), "spec", 0)
+	require.EqualError(t, err, `line 4:5: expected node kind "sequence", got "mapping"`)
+	err = walky.SetPath(root, walky.NewStringNode("x"), "spec", "ports", -3)
+	require.EqualError(t, err, `line 4:12: sequence index -3 out of range`)
+	err = walky.SetPath(root, walky.NewStringNode("x"), 1.5)
+	require.EqualError(t, err, `Unable to make PathMatcher from type float64 (1.5)`)
+}
+
+func TestDeletePath(t *testing.T) {
+	root := parse(t, `
+	spec:
+		containers:
+			- name: web
+			- name: sidecar
+			- name: web
+		labels:
+			app: web
+			tier: frontend
+			80: http
+	`)
+	deleted, err := walky.DeletePath(root, "spec", "containers", 2)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	deleted, err = walky.DeletePath(root, "spec", "containers", -1)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	deleted, err = walky.DeletePath(root, "spec", "labels", "tier")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	deleted, err = walky.DeletePath(root, "spec", "labels", "80")
+	require.NoError(t, err)
+	require.True(t, deleted)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		spec:
+			containers:
+				- name: web
+			labels:
+				app: web
+	`), string(got))
+
+	for _, path := range [][]interface{}{
+		{"spec", "labels", "missing"},
+		{"spec", "containers", 5},
+		{"spec", "containers", -5},
+		{"spec", "missing", "key"},
+		{"missing", "containers", 0},
+		{"spec", "labels", 0},
+		{"spec", "containers", "name"},
+		{"spec", "labels", "app", "nested"},
+	} {
+		deleted, err := walky.DeletePath(root, path...)
+		require.NoError(t, err, "%v", path)
+		require.False(t, deleted, "%v", path)
+	}
+	got2, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, string(got), string(got2))
+
+	_, err = walky.DeletePath(root)
+	require.EqualError(t, err, "empty path")
+	_, err = walky.DeletePath(root, "spec", 1.5)
+	require.EqualError(t, err, "invalid path element type float64 (1.5)")
+}
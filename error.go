@@ -34,6 +34,13 @@ func NewYAMLError(err error, node *yaml.Node) error {
 	}
 }
 
+// Errorf is shorthand for `NewYAMLError(fmt.Errorf(format, args...), node)`,
+// it will return a YAMLError with the formatted message at the position of
+// `node`.
+func Errorf(node *yaml.Node, format string, args ...interface{}) error {
+	return NewYAMLError(fmt.Errorf(format, args...), node)
+}
+
 func (e YAMLError) Error() string {
 	return e.location() + ": " + e.Err.Error()
 }
@@ -130,3 +137,71 @@ func ErrDecode(err error) error {
 	}
 	return err
 }
+
+// YAMLErrors is a list of YAMLError values, used when a check reports
+// problems at multiple positions within a document.
+type YAMLErrors []YAMLError
+
+func (e YAMLErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, ye := range e {
+		msgs = append(msgs, ye.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns each of the YAMLError values, so errors.Is and errors.As
+// will check every error in the list.
+func (e YAMLErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, ye := range e {
+		errs = append(errs, ye)
+	}
+	return errs
+}
+
+// FormatWithSource will render `err` with the line from `source` that the
+// error refers to, with a `^` under the error column, for example:
+//
+//	2 | myint: "abc"
+//	  |        ^
+//	line 2:8 at "abc": cannot unmarshal !!str `abc` into int
+//
+// If `err` is not a YAMLError (or a yaml.TypeError that can be converted
+// with ErrDecode) with a line inside of `source`, then just the error message
+// is returned.
+func FormatWithSource(err error, source []byte) string {
+	ye := YAMLError{}
+	if !errors.As(ErrDecode(err), &ye) || ye.Line < 1 {
+		return err.Error()
+	}
+	lines := strings.Split(string(source), "\n")
+	if ye.Line > len(lines) {
+		return err.Error()
+	}
+	line := strings.TrimSuffix(lines[ye.Line-1], "\r")
+	lineNo := strconv.Itoa(ye.Line)
+	gutter := strings.Repeat(" ", len(lineNo))
+
+	var msg strings.Builder
+	msg.WriteString(lineNo + " | " + line + "\n")
+	if ye.Column > 0 {
+		msg.WriteString(gutter + " | ")
+		col := 1
+		for _, r := range line {
+			if col >= ye.Column {
+				break
+			}
+			// preserve tabs so the caret lines up with the source
+			if r == '\t' {
+				msg.WriteRune('\t')
+			} else {
+				msg.WriteRune(' ')
+			}
+			col++
+		}
+		msg.WriteString("^\n")
+	}
+	msg.WriteString(ErrDecode(err).Error())
+	return msg.String()
+}
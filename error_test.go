@@ -2,6 +2,7 @@ package walky
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"testing"
 
@@ -65,3 +66,83 @@ func TestErrorUnwrap(t *testing.T) {
 	te := &yaml.TypeError{}
 	require.True(t, errors.As(err, &te))
 }
+
+func TestFormatWithSource(t *testing.T) {
+	content := []byte(`
+config:
+  myint: "abc"
+  name: ünïcode
+`)
+	var n yaml.Node
+	err := yaml.Unmarshal(content, &n)
+	require.NoError(t, err)
+
+	err = RangeMap(GetKey(GetKey(&n, "config"), "myint"), func(key, value *yaml.Node) error {
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, `3 |   myint: "abc"
+  |          ^
+line 3:10 at "abc": expected node kind "mapping", got "scalar"`, FormatWithSource(err, content))
+
+	err = NewYAMLError(errors.New("bad name"), GetKey(GetKey(&n, "config"), "name"))
+	require.Equal(t, `4 |   name: ünïcode
+  |         ^
+line 4:9 at "ünïcode": bad name`, FormatWithSource(err, content))
+
+	data := struct {
+		Config struct {
+			MyInt int `yaml:"myint"`
+		} `yaml:"config"`
+	}{}
+	err = n.Decode(&data)
+	require.Error(t, err)
+	require.Equal(t, "3 |   myint: \"abc\"\nline 3: cannot unmarshal !!str `abc` into int", FormatWithSource(err, content))
+
+	require.Equal(t, "EOF", FormatWithSource(io.EOF, content))
+	require.Equal(t, "line 40: oops", FormatWithSource(YAMLError{Line: 40, Err: errors.New("oops")}, content))
+}
+
+func TestErrorf(t *testing.T) {
+	var n yaml.Node
+	content := `
+config:
+  level: verbose
+`
+	err := yaml.Unmarshal([]byte(content), &n)
+	require.NoError(t, err)
+
+	level := GetKey(GetKey(&n, "config"), "level")
+	err = Errorf(level, "invalid level %q, expected one of %v", level.Value, []string{"debug", "info"})
+	require.EqualError(t, err, `line 3:10 at "verbose": invalid level "verbose", expected one of [debug info]`)
+	ye := YAMLError{}
+	require.True(t, errors.As(err, &ye))
+	require.Equal(t, 3, ye.Line)
+	require.Equal(t, 10, ye.Column)
+
+	err = Errorf(level, "reading level: %w", io.ErrUnexpectedEOF)
+	require.EqualError(t, err, `line 3:10 at "verbose": reading level: unexpected EOF`)
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+
+	de := ErrFilename(err, "test.yml")
+	require.EqualError(t, de, `test.yml:3:10 at "verbose": reading level: unexpected EOF`)
+	require.True(t, errors.Is(de, io.ErrUnexpectedEOF))
+}
+
+func TestYAMLErrorsUnwrap(t *testing.T) {
+	var err error = YAMLErrors{
+		{Line: 1, Err: errors.New("first")},
+		{Line: 4, Column: 2, Err: io.ErrUnexpectedEOF},
+	}
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	require.False(t, errors.Is(err, io.EOF))
+
+	var ye YAMLError
+	require.True(t, errors.As(err, &ye))
+	require.Equal(t, 1, ye.Line)
+	require.EqualError(t, ye, "line 1: first")
+
+	wrapped := fmt.Errorf("validating: %w", err)
+	require.True(t, errors.Is(wrapped, io.ErrUnexpectedEOF))
+	require.True(t, errors.As(wrapped, &ye))
+}
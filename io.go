@@ -0,0 +1,399 @@
+package walky
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Directives returns the directive lines (such as `%YAML 1.2` or
+// `%TAG !e! tag:example.com,2000:`) from the raw YAML `data`.  yaml.v3 does
+// not preserve directives in the decoded yaml.Node tree, so they must be
+// extracted from the source.  Only lines in the directive section before a
+// document start marker (`---`) are considered.
+func Directives(data []byte) []string {
+	directives := []string{}
+	inPreamble := true
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "...":
+			// document end marker, directives may follow for the next
+			// document
+			inPreamble = true
+		case strings.HasPrefix(line, "---"):
+			inPreamble = false
+		case !inPreamble:
+			continue
+		case strings.HasPrefix(line, "%"):
+			directives = append(directives, line)
+		case line == "" || strings.HasPrefix(strings.TrimSpace(line), "#"):
+			continue
+		default:
+			// content without a document start marker
+			inPreamble = false
+		}
+	}
+	return directives
+}
+
+type writeOption struct {
+	directives []string
+	indent     int
+	mode       os.FileMode
+}
+
+// WriteOption is used to configure WriteFile.
+type WriteOption func(*writeOption)
+
+// WithDirectives will cause WriteFile to emit the provided directives (as
+// returned from Directives) before the document, followed by a document start
+// marker.  Any `%TAG` directives will also be applied to the document, so tags
+// using the tag prefix will be written with the tag handle.
+func WithDirectives(directives ...string) WriteOption {
+	return func(o *writeOption) {
+		o.directives = append(o.directives, directives...)
+	}
+}
+
+// WithIndent will cause WriteFile to indent nested nodes by `n` spaces
+// instead of the default of 4.
+func WithIndent(n int) WriteOption {
+	return func(o *writeOption) {
+		o.indent = n
+	}
+}
+
+// WithFileMode will cause WriteFile to create the file with `mode` instead of
+// the default of 0644.
+func WithFileMode(mode os.FileMode) WriteOption {
+	return func(o *writeOption) {
+		o.mode = mode
+	}
+}
+
+// WriteFile is a helper function to marshal the yaml.Node and write it to
+// `filepath`.  The content is written to a temporary file in the same
+// directory which is then renamed to `filepath`, so readers will see either
+// the old or new content, never a partial write.
+func WriteFile(filepath string, node *yaml.Node, opts ...WriteOption) error {
+	o := &writeOption{
+		indent: 4,
+		mode:   0o644,
+	}
+	for _, optFunc := range opts {
+		optFunc(o)
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(o.indent)
+	if err := enc.Encode(node); err != nil {
+		return ErrFilename(err, filepath)
+	}
+	if err := enc.Close(); err != nil {
+		return ErrFilename(err, filepath)
+	}
+	content := buf.Bytes()
+	if len(o.directives) > 0 {
+		var err error
+		content, err = applyDirectives(content, o.directives)
+		if err != nil {
+			return ErrFilename(err, filepath)
+		}
+	}
+	return writeFileAtomic(filepath, content, o.mode)
+}
+
+// writeFileAtomic writes `content` to a temporary file next to `filename`,
+// then renames it over `filename`.
+func writeFileAtomic(filename string, content []byte, mode os.FileMode) (err error) {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	fh, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			fh.Close()
+			os.Remove(fh.Name())
+		}
+	}()
+	if _, err = fh.Write(content); err != nil {
+		return err
+	}
+	if err = fh.Sync(); err != nil {
+		return err
+	}
+	if err = fh.Chmod(mode); err != nil {
+		return err
+	}
+	if err = fh.Close(); err != nil {
+		return err
+	}
+	return os.Rename(fh.Name(), filename)
+}
+
+// applyDirectives will prefix the marshaled `content` with the `directives`
+// and rewrite verbatim tags using `%TAG` prefixes to use the tag handle.
+// yaml.v3 cannot emit tag handles other than `!` and `!!` (it escapes the
+// `!` in `!e!`), so the tags are rewritten in the marshaled text.  The
+// content is decoded again to find the position of each tag, so scalar
+// values that happen to contain a verbatim tag are left alone.
+func applyDirectives(content []byte, directives []string) ([]byte, error) {
+	handles := map[string]string{}
+	prefixes := []string{}
+	for _, directive := range directives {
+		fields := strings.Fields(directive)
+		if len(fields) != 3 || fields[0] != "%TAG" {
+			continue
+		}
+		handles[fields[2]] = fields[1]
+		prefixes = append(prefixes, fields[2])
+	}
+	if len(prefixes) > 0 {
+		var err error
+		content, err = rewriteTags(content, handles, prefixes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	for _, directive := range directives {
+		buf.WriteString(directive + "\n")
+	}
+	buf.WriteString("---\n")
+	buf.Write(content)
+	return buf.Bytes(), nil
+}
+
+// rewriteTags replaces each verbatim tag in the marshaled `content` that
+// starts with one of the `prefixes` with the corresponding tag handle.
+func rewriteTags(content []byte, handles map[string]string, prefixes []string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	// tag positions by line, nodes are visited in document order so the
+	// columns for each line are increasing.
+	columns := map[int][]int{}
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(&doc, allNodesWalker(func(node *yaml.Node) error {
+		if node.Kind == yaml.DocumentNode || node.Kind == yaml.AliasNode || node.Line < 1 || node.Line > len(lines) {
+			return nil
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(node.Tag, prefix) {
+				columns[node.Line] = append(columns[node.Line], node.Column)
+				break
+			}
+		}
+		return nil
+	}))
+	for line, cols := range columns {
+		// rewrite from the end of the line so earlier offsets are
+		// not shifted.
+		for i := len(cols) - 1; i >= 0; i-- {
+			lines[line-1] = rewriteTag(lines[line-1], cols[i], handles, prefixes)
+		}
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// rewriteTag replaces the verbatim tag of the node starting at the 1-based
+// character `column` of `line`.
+func rewriteTag(line []byte, column int, handles map[string]string, prefixes []string) []byte {
+	// convert the character column to a byte offset
+	offset := 0
+	for i := 1; i < column && offset < len(line); i++ {
+		_, size := utf8.DecodeRune(line[offset:])
+		offset += size
+	}
+	// the node starts with its anchor if it has one
+	if bytes.HasPrefix(line[offset:], []byte("&")) {
+		end := bytes.IndexByte(line[offset:], ' ')
+		if end < 0 {
+			return line
+		}
+		offset += end + 1
+	}
+	if !bytes.HasPrefix(line[offset:], []byte("!<")) {
+		return line
+	}
+	end := bytes.IndexByte(line[offset:], '>')
+	if end < 0 {
+		return line
+	}
+	tag := string(line[offset+2 : offset+end])
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(tag, prefix) {
+			rewritten := append([]byte{}, line[:offset]...)
+			rewritten = append(rewritten, handles[prefix]+tag[len(prefix):]...)
+			return append(rewritten, line[offset+end+1:]...)
+		}
+	}
+	return line
+}
+
+// ReadFile is a helper function to read a file and return a yaml.Node
+func ReadFile(filepath string) (*yaml.Node, error) {
+	fh, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	node, err := ReadReader(fh)
+	if err != nil {
+		return nil, ErrFilename(err, filepath)
+	}
+	return node, nil
+}
+
+// ReadFileRaw is the same as ReadFile, but will also return the raw content
+// of the file.  This is useful when the original source is needed later, for
+// example to render errors with FormatWithSource.
+func ReadFileRaw(filepath string) (*yaml.Node, []byte, error) {
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, nil, err
+	}
+	node, err := ReadBytes(content)
+	if err != nil {
+		return nil, content, ErrFilename(err, filepath)
+	}
+	return node, content, nil
+}
+
+// ReadReader is the same as ReadFile, but will decode the first document
+// from `r`.  Decode errors are converted with ErrDecode so they will be a
+// YAMLError when the position is known.  Empty input returns an empty
+// yaml.Node.
+func ReadReader(r io.Reader) (*yaml.Node, error) {
+	dec := yaml.NewDecoder(r)
+	var node yaml.Node
+	if err := dec.Decode(&node); err != nil && !errors.Is(err, io.EOF) {
+		return nil, ErrDecode(err)
+	}
+	return &node, nil
+}
+
+// ReadBytes is the same as ReadReader, but will decode the first document
+// from `b`.
+func ReadBytes(b []byte) (*yaml.Node, error) {
+	return ReadReader(bytes.NewReader(b))
+}
+
+// ReadAllFile is the same as ReadFile, but will return every document in the
+// file, for files with multiple documents separated by `---`.  Decode errors
+// will include the filename and the zero-based index of the document that
+// failed.
+func ReadAllFile(filepath string) ([]*yaml.Node, error) {
+	fh, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	nodes, err := ReadAll(fh)
+	if err != nil {
+		return nil, ErrFilename(err, filepath)
+	}
+	return nodes, nil
+}
+
+// ReadAll is the same as ReadReader, but will return every document from `r`.
+// Decode errors will include the zero-based index of the document that
+// failed.  Empty input returns an empty slice.
+func ReadAll(r io.Reader) ([]*yaml.Node, error) {
+	dec := yaml.NewDecoder(r)
+	nodes := []*yaml.Node{}
+	for {
+		var node yaml.Node
+		err := dec.Decode(&node)
+		if errors.Is(err, io.EOF) {
+			return nodes, nil
+		}
+		if err != nil {
+			err = ErrDecode(err)
+			if ye, ok := err.(YAMLError); ok {
+				ye.Err = fmt.Errorf("document %d: %w", len(nodes), ye.Err)
+				return nil, ye
+			}
+			return nil, fmt.Errorf("document %d: %w", len(nodes), err)
+		}
+		nodes = append(nodes, &node)
+	}
+}
+
+// ReadFirst will return the document from the first of the `paths` that
+// exists and can be parsed, which is useful for loading config from a list of
+// candidate locations.  Files that do not exist or fail to parse are skipped.
+// If no file can be read the parse error from the first file that failed is
+// returned (which identifies the file), otherwise an error wrapping
+// os.ErrNotExist is returned.  An empty file returns an empty DocumentNode,
+// the same as ReadMerged.
+func ReadFirst(paths ...string) (*yaml.Node, error) {
+	var firstErr error
+	for _, path := range paths {
+		node, err := ReadFile(path)
+		if err == nil {
+			if node.Kind == 0 {
+				// empty file
+				return NewDocumentNode(), nil
+			}
+			return node, nil
+		}
+		if firstErr == nil && !errors.Is(err, os.ErrNotExist) {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, fmt.Errorf("none of the files exist: %s: %w", strings.Join(paths, ", "), os.ErrNotExist)
+}
+
+// ReadMerged will read each of the `paths` that exist and DeepMerge them in
+// order, so later files override earlier files, for example:
+//
+//	ReadMerged("defaults.yaml", "prod.yaml", "local.yaml")
+//
+// Files that do not exist (and empty files) are skipped, but any other error
+// reading or parsing a file is returned identifying the file.  If none of the
+// files exist an error wrapping os.ErrNotExist is returned.
+func ReadMerged(paths ...string) (*yaml.Node, error) {
+	merged := NewDocumentNode()
+	found := false
+	for _, path := range paths {
+		node, err := ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		if node.Kind == 0 {
+			// empty file
+			continue
+		}
+		if err := DeepMerge(merged, node); err != nil {
+			return nil, ErrFilename(err, path)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("none of the files exist: %s: %w", strings.Join(paths, ", "), os.ErrNotExist)
+	}
+	return merged, nil
+}
@@ -0,0 +1,303 @@
+package walky_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDirectives(t *testing.T) {
+	doc := HereBytes(`
+	# leading comment
+	%YAML 1.1
+	%TAG !e! tag:example.com,2000:app/
+	---
+	name: !e!widget thing
+	%notadirective: value
+	...
+	%TAG !f! tag:example.com,2000:other/
+	--- 
+	other: value
+	`)
+
+	directives := walky.Directives(doc)
+	require.Equal(t, []string{
+		"%YAML 1.1",
+		"%TAG !e! tag:example.com,2000:app/",
+		"%TAG !f! tag:example.com,2000:other/",
+	}, directives)
+
+	require.Empty(t, walky.Directives(HereBytes(`
+	a: 1
+	%b: 2
+	`)))
+
+	doc = HereBytes(`
+	%YAML 1.1
+	%TAG !e! tag:example.com,2000:app/
+	---
+	name: !e!widget thing
+	note: see !<tag:example.com,2000:app/part> for details
+	base: &base !e!part x
+	parts: [!e!part y, !e!bolt z]
+	`)
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	err := os.WriteFile(file, doc, 0o644)
+	require.NoError(t, err)
+
+	root, err := walky.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "tag:example.com,2000:app/widget", walky.GetKey(root, "name").Tag)
+
+	err = walky.WriteFile(file, root, walky.WithDirectives(walky.Directives(doc)...))
+	require.NoError(t, err)
+	got, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, string(doc), string(got))
+}
+
+func TestReadFileRaw(t *testing.T) {
+	doc := HereBytes(`
+	# config
+	name: web
+	ports: [80, 443]
+	`)
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	err := os.WriteFile(file, doc, 0o644)
+	require.NoError(t, err)
+
+	root, raw, err := walky.ReadFileRaw(file)
+	require.NoError(t, err)
+	require.Equal(t, doc, raw)
+	expected, err := walky.ReadFile(file)
+	require.NoError(t, err)
+	require.True(t, walky.Equal(expected, root))
+	require.Equal(t, "web", walky.GetKey(root, "name").Value)
+
+	err = os.WriteFile(file, nil, 0o644)
+	require.NoError(t, err)
+	root, raw, err = walky.ReadFileRaw(file)
+	require.NoError(t, err)
+	require.Empty(t, raw)
+	require.Equal(t, yaml.Kind(0), root.Kind)
+
+	err = os.WriteFile(file, []byte("a: [1, 2\n"), 0o644)
+	require.NoError(t, err)
+	_, raw, err = walky.ReadFileRaw(file)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), file)
+	require.Equal(t, "a: [1, 2\n", string(raw))
+
+	_, _, err = walky.ReadFileRaw(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadFirst(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("a: [\n"), 0o644))
+	require.NoError(t, os.WriteFile(valid, []byte("name: valid\n"), 0o644))
+
+	node, err := walky.ReadFirst(missing, invalid, valid)
+	require.NoError(t, err)
+	require.Equal(t, "valid", walky.GetKey(node, "name").Value)
+
+	_, err = walky.ReadFirst(missing, invalid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), invalid)
+
+	_, err = walky.ReadFirst(missing)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// empty files are an empty document
+	empty := filepath.Join(dir, "empty.yaml")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+	node, err = walky.ReadFirst(missing, empty, valid)
+	require.NoError(t, err)
+	require.Equal(t, yaml.DocumentNode, node.Kind)
+	require.Empty(t, node.Content)
+	err = walky.SetPath(node, walky.NewStringNode("web"), "name")
+	require.NoError(t, err)
+	require.Equal(t, "web", walky.GetKey(node, "name").Value)
+}
+
+func TestReadMerged(t *testing.T) {
+	dir := t.TempDir()
+	defaults := filepath.Join(dir, "defaults.yaml")
+	local := filepath.Join(dir, "local.yaml")
+	require.NoError(t, os.WriteFile(defaults, HereBytes(`
+		name: web
+		replicas: 1
+		resources:
+			cpu: 1
+	`), 0o644))
+	require.NoError(t, os.WriteFile(local, HereBytes(`
+		replicas: 3
+		resources:
+			memory: 1Gi
+	`), 0o644))
+
+	node, err := walky.ReadMerged(defaults, filepath.Join(dir, "missing.yaml"), local)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		replicas: 3
+		resources:
+			cpu: 1
+			memory: 1Gi
+	`), string(got))
+
+	// the defaults file is not modified
+	node, err = walky.ReadFile(defaults)
+	require.NoError(t, err)
+	require.Equal(t, "1", walky.GetKey(node, "replicas").Value)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("replicas: [\n"), 0o644))
+	_, err = walky.ReadMerged(defaults, invalid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), invalid)
+
+	_, err = walky.ReadMerged(filepath.Join(dir, "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	root := parse(t, `
+	name: web
+	spec:
+		ports: [80]
+		env:
+			- DEBUG
+	`)
+
+	err := walky.WriteFile(file, root)
+	require.NoError(t, err)
+	got, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		spec:
+			ports: [80]
+			env:
+				- DEBUG
+	`), string(got))
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o644), info.Mode().Perm())
+
+	// overwrite the existing file
+	err = walky.WriteFile(file, root, walky.WithIndent(2), walky.WithFileMode(0o600))
+	require.NoError(t, err)
+	got, err = os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "name: web\nspec:\n  ports: [80]\n  env:\n    - DEBUG\n", string(got))
+	info, err = os.Stat(file)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// a failed write leaves the original content intact
+	err = walky.WriteFile(file, &yaml.Node{Kind: 99})
+	require.Error(t, err)
+	got, err = os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, "name: web\nspec:\n  ports: [80]\n  env:\n    - DEBUG\n", string(got))
+
+	err = walky.WriteFile(filepath.Join(dir, "missing", "config.yaml"), root)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadReader(t *testing.T) {
+	doc := HereBytes(`
+	name: web
+	ports: [80]
+	---
+	second: doc
+	`)
+	node, err := walky.ReadReader(bytes.NewReader(doc))
+	require.NoError(t, err)
+	require.Equal(t, yaml.DocumentNode, node.Kind)
+	require.Equal(t, "web", walky.GetKey(node, "name").Value)
+	require.Nil(t, walky.GetKey(node, "second"))
+
+	fromBytes, err := walky.ReadBytes(doc)
+	require.NoError(t, err)
+	require.True(t, walky.Equal(node, fromBytes))
+
+	// empty input is not an error
+	node, err = walky.ReadBytes(nil)
+	require.NoError(t, err)
+	require.Equal(t, yaml.Kind(0), node.Kind)
+
+	_, err = walky.ReadReader(strings.NewReader("a: [1, 2\n"))
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), ".yaml")
+
+	// the same errors are returned from ReadFile, with the filename
+	file := filepath.Join(t.TempDir(), "test.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("a: [1, 2\n"), 0o644))
+	_, fileErr := walky.ReadFile(file)
+	require.EqualError(t, fileErr, file+": "+err.Error())
+}
+
+func TestReadAll(t *testing.T) {
+	doc := HereBytes(`
+	kind: Deployment
+	---
+	kind: Service
+	---
+	# empty document
+	---
+	kind: ConfigMap
+	`)
+	file := filepath.Join(t.TempDir(), "manifests.yaml")
+	require.NoError(t, os.WriteFile(file, doc, 0o644))
+
+	nodes, err := walky.ReadAllFile(file)
+	require.NoError(t, err)
+	require.Len(t, nodes, 4)
+	kinds := []string{}
+	for _, node := range nodes {
+		require.Equal(t, yaml.DocumentNode, node.Kind)
+		if kind := walky.GetKey(node, "kind"); kind != nil {
+			kinds = append(kinds, kind.Value)
+		}
+	}
+	require.Equal(t, []string{"Deployment", "Service", "ConfigMap"}, kinds)
+
+	fromReader, err := walky.ReadAll(bytes.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, fromReader, 4)
+
+	nodes, err = walky.ReadAll(strings.NewReader(""))
+	require.NoError(t, err)
+	require.Empty(t, nodes)
+
+	require.NoError(t, os.WriteFile(file, HereBytes(`
+	kind: Deployment
+	---
+	kind: [Service
+	`), 0o644))
+	_, err = walky.ReadAllFile(file)
+	require.EqualError(t, err, file+": document 1: yaml: line 2: did not find expected ',' or ']'")
+
+	_, err = walky.ReadAllFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,188 @@
+package walky
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ToJSON will convert the node tree to compact JSON.  Aliases and `!!merge`
+// keys are expanded first, and mapping keys are written in document order.
+// Scalars are converted according to their tag, so `!!int`, `!!float` and
+// `!!bool` values are written as JSON numbers and booleans, `!!null` as
+// `null` and all other scalars as strings.  JSON requires string keys, so a
+// YAMLError is returned if a mapping key is not a `!!str` scalar.  A YAMLError
+// is also returned for values JSON cannot represent, such as `.inf`, `.nan`
+// and recursive aliases.
+func ToJSON(node *yaml.Node) ([]byte, error) {
+	resolved, err := resolveNode(UnwrapDocument(node), map[*yaml.Node]bool{})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, resolved); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		// only empty documents remain after UnwrapDocument
+		buf.WriteString("null")
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode || key.ShortTag() != "!!str" {
+				return Errorf(key, "JSON requires string keys, got %s %s", key.ShortTag(), KindString(key.Kind))
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, key.Value); err != nil {
+				return NewYAMLError(err, key)
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, elem := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		return writeJSONScalar(buf, node)
+	default:
+		// only recursive aliases remain after resolveNode
+		return Errorf(node, "JSON cannot represent recursive alias %q", node.Value)
+	}
+	return nil
+}
+
+func writeJSONScalar(buf *bytes.Buffer, node *yaml.Node) error {
+	var value interface{} = node.Value
+	switch node.ShortTag() {
+	case "!!null":
+		value = nil
+	case "!!bool", "!!int":
+		if err := node.Decode(&value); err != nil {
+			return NewYAMLError(err, node)
+		}
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return NewYAMLError(err, node)
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return Errorf(node, "JSON cannot represent float value %s", node.Value)
+		}
+		value = f
+	}
+	if err := writeJSONValue(buf, value); err != nil {
+		return NewYAMLError(err, node)
+	}
+	return nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, value interface{}) error {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	buf.Write(out)
+	return nil
+}
+
+// FromJSON will parse the JSON `data` into a node tree, which is the inverse
+// of ToJSON.  Objects become mappings with the keys in their original order,
+// arrays become sequences and scalars are tagged according to their JSON
+// type.  Numbers with a fractional or exponent part are tagged `!!float`, all
+// other numbers are tagged `!!int`, and the number is kept exactly as written.
+// An error is returned if `data` is not a single valid JSON value.
+func FromJSON(data []byte) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	node, err := readJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid JSON: unexpected data after top-level value")
+	}
+	return node, nil
+}
+
+func readJSON(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '{' {
+			node := NewMappingNode()
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				// object keys are always strings
+				node.Content = append(node.Content, NewStringNode(key.(string)), value)
+			}
+			_, err = dec.Token()
+			return node, err
+		}
+		node := NewSequenceNode()
+		for dec.More() {
+			elem, err := readJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, elem)
+		}
+		_, err = dec.Token()
+		return node, err
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Tag:   tag,
+			Value: v.String(),
+		}, nil
+	case string:
+		return NewStringNode(v), nil
+	case bool:
+		return NewBoolNode(v), nil
+	default:
+		return NewNullNode(), nil
+	}
+}
@@ -0,0 +1,143 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestToJSON(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+		replicas: 3
+	service:
+		<<: *defaults
+		name: "web <prod>"
+		replicas: 5
+		ratio: 0.5
+		big: 1e3
+		hex: 0x1F
+		enabled: true
+		version: "1.0"
+		missing: ~
+		ports: [80, 443]
+		tags: []
+		labels: {}
+		created: 2023-01-02T03:04:05Z
+	`)
+	got, err := walky.ToJSON(root)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"defaults": {"image": "web:1.0", "replicas": 3},
+		"service": {
+			"image": "web:1.0",
+			"replicas": 5,
+			"name": "web <prod>",
+			"ratio": 0.5,
+			"big": 1000,
+			"hex": 31,
+			"enabled": true,
+			"version": "1.0",
+			"missing": null,
+			"ports": [80, 443],
+			"tags": [],
+			"labels": {},
+			"created": "2023-01-02T03:04:05Z"
+		}
+	}`, string(got))
+	// keys are written in document order
+	require.Regexp(t, `^\{"defaults":\{"image":"web:1.0","replicas":3\},"service":\{"image":`, string(got))
+
+	got, err = walky.ToJSON(parse(t, `[a, 1, true]`))
+	require.NoError(t, err)
+	require.Equal(t, `["a",1,true]`, string(got))
+
+	got, err = walky.ToJSON(&yaml.Node{Kind: yaml.DocumentNode})
+	require.NoError(t, err)
+	require.Equal(t, `null`, string(got))
+
+	_, err = walky.ToJSON(parse(t, `
+	ports:
+		80: http
+	`))
+	require.EqualError(t, err, `line 2:5 at "80": JSON requires string keys, got !!int scalar`)
+
+	_, err = walky.ToJSON(parse(t, `
+	? [a, b]
+	: value
+	`))
+	require.EqualError(t, err, `line 1:3: JSON requires string keys, got !!seq sequence`)
+
+	_, err = walky.ToJSON(parse(t, `limit: .inf`))
+	require.EqualError(t, err, `line 1:8 at ".inf": JSON cannot represent float value .inf`)
+}
+
+func TestFromJSON(t *testing.T) {
+	node, err := walky.FromJSON([]byte(`{
+		"name": "web",
+		"replicas": 3,
+		"ratio": 0.5,
+		"big": 1e3,
+		"enabled": true,
+		"version": "1.0",
+		"count": "3",
+		"missing": null,
+		"ports": [80, 443],
+		"zeta": {"b": 1, "a": [{}, []]}
+	}`))
+	require.NoError(t, err)
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		replicas: 3
+		ratio: 0.5
+		big: 1e3
+		enabled: true
+		version: "1.0"
+		count: "3"
+		missing:
+		ports:
+			- 80
+			- 443
+		zeta:
+			b: 1
+			a:
+				- {}
+				- []
+	`), string(got))
+
+	tags := []string{}
+	err = walky.RangeMap(node, func(key, value *yaml.Node) error {
+		require.Equal(t, "!!str", key.Tag)
+		tags = append(tags, value.Tag)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"!!str", "!!int", "!!float", "!!float", "!!bool", "!!str", "!!str", "!!null", "!!seq", "!!map",
+	}, tags)
+
+	// round trip through ToJSON
+	out, err := walky.ToJSON(node)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"name": "web", "replicas": 3, "ratio": 0.5, "big": 1000, "enabled": true,
+		"version": "1.0", "count": "3", "missing": null, "ports": [80, 443],
+		"zeta": {"b": 1, "a": [{}, []]}
+	}`, string(out))
+
+	node, err = walky.FromJSON([]byte(`"scalar"`))
+	require.NoError(t, err)
+	require.Equal(t, "scalar", node.Value)
+
+	_, err = walky.FromJSON([]byte(`{"a": 1`))
+	require.Error(t, err)
+	_, err = walky.FromJSON([]byte(``))
+	require.Error(t, err)
+	_, err = walky.FromJSON([]byte(`{"a": 1} {"b": 2}`))
+	require.EqualError(t, err, "invalid JSON: unexpected data after top-level value")
+}
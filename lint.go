@@ -0,0 +1,80 @@
+package walky
+
+import (
+	"bytes"
+	"errors"
+	"unicode/utf8"
+)
+
+var (
+	// ErrTrailingWhitespace is reported by LintSource for lines that end with
+	// spaces or tabs.
+	ErrTrailingWhitespace = errors.New("trailing whitespace")
+	// ErrTabIndentation is reported by LintSource for lines indented with
+	// tabs.
+	ErrTabIndentation = errors.New("tab character in indentation")
+	// ErrTabInValue is reported by LintSource for tab characters found after
+	// the indentation of a line.
+	ErrTabInValue = errors.New("tab character in value")
+	// ErrMissingNewline is reported by LintSource when the source does not
+	// end with a newline.
+	ErrMissingNewline = errors.New("missing trailing newline")
+)
+
+// LintSource will scan the raw YAML `data` for formatting issues that are not
+// preserved in a decoded yaml.Node tree.  A YAMLError is returned for each
+// issue found with the Line and Column where the issue starts, the underlying
+// error will be one of ErrTrailingWhitespace, ErrTabIndentation, ErrTabInValue
+// or ErrMissingNewline.
+func LintSource(data []byte) []YAMLError {
+	errs := []YAMLError{}
+	if len(data) == 0 {
+		return errs
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	missingNewline := len(lines[len(lines)-1]) > 0
+	if !missingNewline {
+		// data ends with a newline, so the final "line" is empty
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lineNo := i + 1
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		// columns count characters, the same as yaml.v3
+		column := func(offset int) int {
+			return utf8.RuneCount(line[:offset]) + 1
+		}
+		content := bytes.TrimRight(line, " \t")
+		if len(content) != len(line) {
+			errs = append(errs, YAMLError{
+				Line:   lineNo,
+				Column: column(len(content)),
+				Err:    ErrTrailingWhitespace,
+			})
+		}
+		indent := len(content) - len(bytes.TrimLeft(content, " \t"))
+		if ix := bytes.IndexByte(content[:indent], '\t'); ix >= 0 {
+			errs = append(errs, YAMLError{
+				Line:   lineNo,
+				Column: column(ix),
+				Err:    ErrTabIndentation,
+			})
+		}
+		if ix := bytes.IndexByte(content[indent:], '\t'); ix >= 0 {
+			errs = append(errs, YAMLError{
+				Line:   lineNo,
+				Column: column(indent + ix),
+				Err:    ErrTabInValue,
+			})
+		}
+	}
+	if missingNewline {
+		last := bytes.TrimSuffix(lines[len(lines)-1], []byte("\r"))
+		errs = append(errs, YAMLError{
+			Line:   len(lines),
+			Column: utf8.RuneCount(last) + 1,
+			Err:    ErrMissingNewline,
+		})
+	}
+	return errs
+}
@@ -0,0 +1,75 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLintSource(t *testing.T) {
+	type issue struct {
+		Line   int
+		Column int
+		Err    error
+	}
+	for _, tt := range []struct {
+		Name     string
+		Input    string
+		Expected []issue
+	}{{
+		Name:     src(),
+		Input:    "a: 1\nb: 2\n",
+		Expected: []issue{},
+	}, {
+		Name:  src(),
+		Input: "a: 1 \nb: 2\nc: 3\t\n",
+		Expected: []issue{
+			{1, 5, walky.ErrTrailingWhitespace},
+			{3, 5, walky.ErrTrailingWhitespace},
+		},
+	}, {
+		Name:  src(),
+		Input: "a:\n\tb: 1\n  \tc: 2\n",
+		Expected: []issue{
+			{2, 1, walky.ErrTabIndentation},
+			{3, 3, walky.ErrTabIndentation},
+		},
+	}, {
+		Name:  src(),
+		Input: "a: 1\nb: 2",
+		Expected: []issue{
+			{2, 5, walky.ErrMissingNewline},
+		},
+	}, {
+		Name:  src(),
+		Input: "a: x\ty\nb: \"q\tr\"\n",
+		Expected: []issue{
+			{1, 5, walky.ErrTabInValue},
+			{2, 6, walky.ErrTabInValue},
+		},
+	}, {
+		Name:  src(),
+		Input: "a: 1\r\nb: 2 \r\nc: 3",
+		Expected: []issue{
+			{2, 5, walky.ErrTrailingWhitespace},
+			{3, 5, walky.ErrMissingNewline},
+		},
+	}, {
+		Name:  src(),
+		Input: "name: café \ncity: zürich\tbern\nemoji: 🚀",
+		Expected: []issue{
+			{1, 11, walky.ErrTrailingWhitespace},
+			{2, 13, walky.ErrTabInValue},
+			{3, 9, walky.ErrMissingNewline},
+		},
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			got := []issue{}
+			for _, ye := range walky.LintSource([]byte(tt.Input)) {
+				got = append(got, issue{ye.Line, ye.Column, ye.Err})
+			}
+			require.Equal(t, tt.Expected, got)
+		})
+	}
+}
@@ -0,0 +1,290 @@
+package walky
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Conflict describes a path changed differently in both `mine` and `theirs`
+// by ThreeWayMerge.  Any of the nodes will be nil if the path is not present
+// in that document (ie it was added or deleted).
+type Conflict struct {
+	Path   []interface{}
+	Base   *yaml.Node
+	Mine   *yaml.Node
+	Theirs *yaml.Node
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: base %s, mine %s, theirs %s",
+		PathString(c.Path...), conflictValue(c.Base), conflictValue(c.Mine), conflictValue(c.Theirs),
+	)
+}
+
+func conflictValue(node *yaml.Node) string {
+	if node == nil {
+		return "<absent>"
+	}
+	return flowString(node)
+}
+
+// ThreeWayMerge will merge the changes made to `base` in both `mine` and
+// `theirs`.  The result is a new node based on `mine`, so the order and
+// comments from `mine` are preserved, with the changes from `theirs` applied:
+//   - values changed only in `theirs` are updated (keeping the comments from
+//     `mine`)
+//   - keys added only in `theirs` are appended to the mapping
+//   - keys deleted only in `theirs` are deleted
+//
+// Mappings changed in both are merged key by key.  When a path is changed in
+// both `mine` and `theirs` to different values (including modified in one and
+// deleted in the other) a Conflict is reported and the value from `mine` is
+// kept.  Sequences are not merged element by element, so different changes
+// to the same sequence are a conflict.  Merge keys (`<<`) are treated as
+// regular keys.  An error is returned if any of the documents contain
+// malformed mappings.
+func ThreeWayMerge(base, mine, theirs *yaml.Node) (*yaml.Node, []Conflict, error) {
+	conflicts := []Conflict{}
+	merged, err := merge3(UnwrapDocument(base), UnwrapDocument(mine), UnwrapDocument(theirs), nil, &conflicts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return merged, conflicts, nil
+}
+
+func merge3(base, mine, theirs *yaml.Node, path []interface{}, conflicts *[]Conflict) (*yaml.Node, error) {
+	switch {
+	case Equal(mine, theirs), Equal(base, theirs):
+		return CopyNode(mine), nil
+	case Equal(base, mine):
+		merged := CopyNode(mine)
+		AssignNode(merged, CopyNode(theirs))
+		return merged, nil
+	}
+	m, t := Indirect(mine), Indirect(theirs)
+	if m.Kind != yaml.MappingNode || t.Kind != yaml.MappingNode {
+		*conflicts = append(*conflicts, Conflict{Path: path, Base: base, Mine: mine, Theirs: theirs})
+		return CopyNode(mine), nil
+	}
+	var b *yaml.Node
+	if base != nil && Indirect(base).Kind == yaml.MappingNode {
+		b = Indirect(base)
+	}
+	for _, node := range []*yaml.Node{b, m, t} {
+		if node != nil && len(node.Content)%2 != 0 {
+			return nil, NewYAMLError(
+				fmt.Errorf("unexpected node content length %d, must be even", len(node.Content)),
+				node,
+			)
+		}
+	}
+	lookup := func(mapNode, key *yaml.Node) *yaml.Node {
+		if mapNode == nil {
+			return nil
+		}
+		for i := 0; i < len(mapNode.Content); i += 2 {
+			if Equal(mapNode.Content[i], key) {
+				return mapNode.Content[i+1]
+			}
+		}
+		return nil
+	}
+	child := func(key *yaml.Node) []interface{} {
+		return append(path[:len(path):len(path)], key.Value)
+	}
+
+	merged := ShallowCopyNode(m)
+	merged.Anchor = ""
+	merged.Content = nil
+	for i := 0; i < len(m.Content); i += 2 {
+		key, mv := m.Content[i], m.Content[i+1]
+		bv, tv := lookup(b, key), lookup(t, key)
+		switch {
+		case tv == nil && bv == nil:
+			// added in mine
+			merged.Content = append(merged.Content, CopyNode(key), CopyNode(mv))
+		case tv == nil && Equal(bv, mv):
+			// deleted in theirs
+		case tv == nil:
+			*conflicts = append(*conflicts, Conflict{Path: child(key), Base: bv, Mine: mv})
+			merged.Content = append(merged.Content, CopyNode(key), CopyNode(mv))
+		default:
+			value, err := merge3(bv, mv, tv, child(key), conflicts)
+			if err != nil {
+				return nil, err
+			}
+			merged.Content = append(merged.Content, CopyNode(key), value)
+		}
+	}
+	for i := 0; i < len(t.Content); i += 2 {
+		key, tv := t.Content[i], t.Content[i+1]
+		if lookup(m, key) != nil {
+			continue
+		}
+		bv := lookup(b, key)
+		switch {
+		case bv == nil:
+			// added in theirs
+			merged.Content = append(merged.Content, CopyNode(key), CopyNode(tv))
+		case !Equal(bv, tv):
+			// deleted in mine, modified in theirs
+			*conflicts = append(*conflicts, Conflict{Path: child(key), Base: bv, Theirs: tv})
+		}
+	}
+	return merged, nil
+}
+
+// MergeOption can be provided to DeepMerge to modify how nodes are merged.
+type MergeOption func(*mergeOptions)
+
+type mergeOptions struct {
+	provenance     string
+	appendSequence bool
+}
+
+// WithSequenceReplace is a MergeOption for DeepMerge that will replace
+// sequences in `dest` with the corresponding sequence from `src`.  This is
+// the default.
+func WithSequenceReplace() MergeOption {
+	return func(opts *mergeOptions) {
+		opts.appendSequence = false
+	}
+}
+
+// WithSequenceAppend is a MergeOption for DeepMerge that will append the
+// elements of sequences in `src` to the corresponding sequence in `dest`,
+// rather than replacing the `dest` sequence.
+func WithSequenceAppend() MergeOption {
+	return func(opts *mergeOptions) {
+		opts.appendSequence = true
+	}
+}
+
+// WithProvenanceComments is a MergeOption that will add a line comment like
+// `# from prod-overrides` to each node inserted or overridden by DeepMerge,
+// where `prod-overrides` is the `layerName`.  Provenance comments from an
+// earlier merge are replaced, so when merging several layers each node is
+// annotated with the last layer that set it.  Only comments naming a layer
+// previously passed to WithProvenanceComments are replaced, so other comments
+// like `# from vendor docs` are kept.
+func WithProvenanceComments(layerName string) MergeOption {
+	provenanceLayers.Lock()
+	provenanceLayers.names[layerName] = true
+	provenanceLayers.Unlock()
+	return func(opts *mergeOptions) {
+		opts.provenance = layerName
+	}
+}
+
+// DeepMerge will merge `src` into `dest`.  Mappings are merged recursively,
+// with values from `src` overriding values in `dest` and keys only in `src`
+// appended to the `dest` mapping.  Sequences in `src` replace the
+// corresponding sequence in `dest`, unless WithSequenceAppend is used.  All
+// other nodes in `src` replace the corresponding node in `dest`, as does any
+// `src` node that is a different kind than the `dest` node.  Replaced nodes are updated
+// with AssignNode, so the comments on `dest` are preserved.  Keys included in
+// `src` with `!!merge` are merged as regular keys.  Nodes from `src` are
+// copied, so `src` is never modified or shared with `dest`.  Merging an
+// empty `src` document does nothing, and an error is returned if either node
+// is nil.
+func DeepMerge(dest, src *yaml.Node, opts ...MergeOption) error {
+	if dest == nil || src == nil {
+		return fmt.Errorf("DeepMerge called with nil node")
+	}
+	o := &mergeOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if src.Kind == yaml.DocumentNode && len(src.Content) == 0 {
+		return nil
+	}
+	if dest.Kind == yaml.DocumentNode && len(dest.Content) == 0 {
+		dest.Content = []*yaml.Node{CopyNode(Indirect(src))}
+		o.annotate(nil, dest.Content[0])
+		return nil
+	}
+	return deepMerge(nil, UnwrapDocument(dest), Indirect(src), o)
+}
+
+func deepMerge(parent, dest, src *yaml.Node, opts *mergeOptions) error {
+	if opts.appendSequence && dest.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode {
+		for _, elem := range src.Content {
+			added := CopyNode(elem)
+			dest.Content = append(dest.Content, added)
+			opts.annotate(dest, added)
+		}
+		return nil
+	}
+	if dest.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		AssignNode(dest, CopyNode(src))
+		opts.annotate(parent, dest)
+		return nil
+	}
+	if len(dest.Content)%2 != 0 {
+		return NewYAMLError(
+			fmt.Errorf("unexpected node content length %d, must be even", len(dest.Content)),
+			dest,
+		)
+	}
+	return RangeMap(src, func(key, value *yaml.Node) error {
+		for i := 0; i < len(dest.Content); i += 2 {
+			if Equal(dest.Content[i], key) {
+				return deepMerge(dest, dest.Content[i+1], Indirect(value), opts)
+			}
+		}
+		added := CopyNode(value)
+		dest.Content = append(dest.Content, CopyNode(key), added)
+		opts.annotate(dest, added)
+		return nil
+	})
+}
+
+// annotate will add the provenance comment to `node`, if requested.
+func (opts *mergeOptions) annotate(parent, node *yaml.Node) {
+	if opts.provenance == "" {
+		return
+	}
+	node.LineComment = stripProvenance(node.LineComment)
+	node.HeadComment = stripProvenance(node.HeadComment)
+	if parent != nil && parent.Kind == yaml.MappingNode {
+		for i := 1; i < len(parent.Content); i += 2 {
+			if parent.Content[i] == node {
+				parent.Content[i-1].LineComment = stripProvenance(parent.Content[i-1].LineComment)
+			}
+		}
+	}
+	annotateNode(parent, node, "from "+opts.provenance)
+}
+
+// provenanceLayers records every layer name passed to WithProvenanceComments,
+// so only comments added by annotate are removed when a node is annotated
+// again.
+var provenanceLayers = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: map[string]bool{}}
+
+// stripProvenance removes any provenance comment added by
+// WithProvenanceComments from `comment`.  annotate only ever appends to the
+// last line, so any other comments are left untouched.
+func stripProvenance(comment string) string {
+	lines := strings.Split(comment, "\n")
+	last := len(lines) - 1
+	provenanceLayers.Lock()
+	for name := range provenanceLayers.names {
+		suffix := "# from " + name
+		rest := strings.TrimSuffix(lines[last], suffix)
+		if rest != lines[last] && (rest == "" || strings.HasSuffix(rest, " ")) {
+			lines[last] = strings.TrimRight(rest, " ")
+			break
+		}
+	}
+	provenanceLayers.Unlock()
+	if lines[last] == "" {
+		lines = lines[:last]
+	}
+	return strings.Join(lines, "\n")
+}
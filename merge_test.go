@@ -0,0 +1,233 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestThreeWayMerge(t *testing.T) {
+	base := parse(t, `
+	name: web
+	replicas: 1
+	image: web:1.0
+	ports: [80]
+	debug: false
+	`)
+	mine := parse(t, `
+	name: web
+	# scaled up for production
+	replicas: 5
+	image: web:1.0 # pinned
+	ports: [80]
+	tls: true
+	`)
+	theirs := parse(t, `
+	name: web
+	replicas: 1
+	image: web:2.0
+	ports: [80, 443]
+	debug: false
+	resources:
+		cpu: 1
+	`)
+
+	merged, conflicts, err := walky.ThreeWayMerge(base, mine, theirs)
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+	got, err := yaml.Marshal(merged)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		# scaled up for production
+		replicas: 5
+		image: web:2.0 # pinned
+		ports: [80, 443]
+		tls: true
+		resources:
+			cpu: 1
+	`), string(got))
+
+	t.Run("conflicts", func(t *testing.T) {
+		theirs := parse(t, `
+		name: web
+		replicas: 3
+		image: web:2.0
+		ports: [80, 443]
+		`)
+		mine := parse(t, `
+		name: web
+		replicas: 5
+		image: web:1.0
+		ports: [8080]
+		debug: true
+		`)
+		merged, conflicts, err := walky.ThreeWayMerge(base, mine, theirs)
+		require.NoError(t, err)
+		summary := []string{}
+		for _, c := range conflicts {
+			summary = append(summary, c.String())
+		}
+		require.Equal(t, []string{
+			"replicas: base 1, mine 5, theirs 3",
+			"ports: base [80], mine [8080], theirs [80, 443]",
+			"debug: base false, mine true, theirs <absent>",
+		}, summary)
+
+		// mine wins for conflicts
+		got, err := yaml.Marshal(merged)
+		require.NoError(t, err)
+		require.Equal(t, Here(`
+			name: web
+			replicas: 5
+			image: web:2.0
+			ports: [8080]
+			debug: true
+		`), string(got))
+	})
+}
+
+func TestDeepMergeProvenance(t *testing.T) {
+	dest := parse(t, `
+	name: web # the name
+	replicas: 1
+	image: web:1.0
+	resources:
+		cpu: 1
+	`)
+	staging := parse(t, `
+	replicas: 2
+	resources:
+		memory: 1Gi
+	`)
+	prod := parse(t, `
+	replicas: 5
+	ports: [80, 443]
+	tls:
+		enabled: true
+	`)
+	err := walky.DeepMerge(dest, staging, walky.WithProvenanceComments("staging"))
+	require.NoError(t, err)
+	err = walky.DeepMerge(dest, prod, walky.WithProvenanceComments("prod-overrides"))
+	require.NoError(t, err)
+
+	got, err := yaml.Marshal(dest)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web # the name
+		replicas: 5 # from prod-overrides
+		image: web:1.0
+		resources:
+			cpu: 1
+			memory: 1Gi # from staging
+		ports: [80, 443] # from prod-overrides
+		tls: # from prod-overrides
+			enabled: true
+	`), string(got))
+
+	// only comments naming a layer are replaced, user comments starting
+	// with "# from" are kept
+	dest = parse(t, `
+	image: web:1.0 # copied # from upstream
+	port: 80 # from vendor docs
+	`)
+	err = walky.DeepMerge(dest, parse(t, `image: web:1.1`), walky.WithProvenanceComments("base.yaml"))
+	require.NoError(t, err)
+	err = walky.DeepMerge(dest, parse(t, `{image: web:1.2, port: 81}`), walky.WithProvenanceComments("prod"))
+	require.NoError(t, err)
+	got, err = yaml.Marshal(dest)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		image: web:1.2 # copied # from upstream # from prod
+		port: 81 # from vendor docs # from prod
+	`), string(got))
+
+	// without the option no comments are added
+	dest = parse(t, `a: 1`)
+	err = walky.DeepMerge(dest, parse(t, `{a: 2, b: 3}`))
+	require.NoError(t, err)
+	got, err = yaml.Marshal(dest)
+	require.NoError(t, err)
+	require.Equal(t, "a: 2\nb: 3\n", string(got))
+}
+
+func TestDeepMerge(t *testing.T) {
+	base := `
+	# service definition
+	name: web # the name
+	ports:
+		- 80
+	env:
+		DEBUG: "false"
+	limits: unlimited
+	`
+	overlay := parse(t, `
+	ports:
+		- 443
+	env:
+		DEBUG: "true"
+		LEVEL: info
+	limits:
+		cpu: 2
+	`)
+
+	dest := parse(t, base)
+	err := walky.DeepMerge(dest, overlay)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(dest)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		# service definition
+		name: web # the name
+		ports:
+			- 443
+		env:
+			DEBUG: "true"
+			LEVEL: info
+		limits:
+			cpu: 2
+	`), string(got))
+
+	dest = parse(t, base)
+	err = walky.DeepMerge(dest, overlay, walky.WithSequenceAppend())
+	require.NoError(t, err)
+	got, err = yaml.Marshal(dest)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		# service definition
+		name: web # the name
+		ports:
+			- 80
+			- 443
+		env:
+			DEBUG: "true"
+			LEVEL: info
+		limits:
+			cpu: 2
+	`), string(got))
+
+	// the last sequence option wins
+	dest = parse(t, base)
+	err = walky.DeepMerge(dest, overlay, walky.WithSequenceAppend(), walky.WithSequenceReplace())
+	require.NoError(t, err)
+	ports := walky.GetKey(dest, "ports")
+	require.Len(t, ports.Content, 1)
+	require.Equal(t, "443", ports.Content[0].Value)
+
+	// src is not shared with dest
+	walky.GetKey(walky.GetKey(dest, "env"), "LEVEL").Value = "debug"
+	require.Equal(t, "info", walky.GetKey(walky.GetKey(overlay, "env"), "LEVEL").Value)
+
+	// empty src documents are ignored
+	dest = parse(t, base)
+	err = walky.DeepMerge(dest, &yaml.Node{Kind: yaml.DocumentNode})
+	require.NoError(t, err)
+	require.Equal(t, parse(t, base), dest)
+
+	err = walky.DeepMerge(dest, nil)
+	require.EqualError(t, err, "DeepMerge called with nil node")
+	err = walky.DeepMerge(nil, overlay)
+	require.EqualError(t, err, "DeepMerge called with nil node")
+}
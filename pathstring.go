@@ -0,0 +1,181 @@
+package walky
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EscapePathSegment will return `key` as a path segment suitable for use
+// with PathString.  Keys that contain `.`, `[`, `]`, `"` or `\`, or that are
+// empty, are quoted in brackets (ie `["a.b"]`), all other keys are returned
+// unchanged.
+func EscapePathSegment(key string) string {
+	if key != "" && !strings.ContainsAny(key, `.[]"\`) {
+		return key
+	}
+	return "[" + strconv.Quote(key) + "]"
+}
+
+// UnescapePathSegment is the inverse of EscapePathSegment, it will return the
+// key for the path segment `s`.
+func UnescapePathSegment(s string) string {
+	if strings.HasPrefix(s, `["`) && strings.HasSuffix(s, `"]`) {
+		if key, err := strconv.Unquote(s[1 : len(s)-1]); err == nil {
+			return key
+		}
+	}
+	return s
+}
+
+// PathString will render the `path` elements (as accepted by WalkPath) as a
+// single string, for example `spec.containers[0].image`.  String elements
+// are escaped with EscapePathSegment and int elements are rendered as
+// `[index]`.  *yaml.Node elements are rendered with their scalar value, or in
+// flow style for complex keys.  The result can be parsed back to the path with ParsePathString.
+func PathString(path ...interface{}) string {
+	var buf strings.Builder
+	for _, p := range path {
+		var segment string
+		switch pp := p.(type) {
+		case int:
+			segment = "[" + strconv.Itoa(pp) + "]"
+		case string:
+			segment = EscapePathSegment(pp)
+		case *yaml.Node:
+			if key := Indirect(pp); key.Kind == yaml.ScalarNode {
+				segment = EscapePathSegment(key.Value)
+			} else {
+				segment = EscapePathSegment(flowString(key))
+			}
+		default:
+			segment = EscapePathSegment(fmt.Sprint(pp))
+		}
+		if buf.Len() > 0 && !strings.HasPrefix(segment, "[") {
+			buf.WriteString(".")
+		}
+		buf.WriteString(segment)
+	}
+	return buf.String()
+}
+
+// ParsePathString will parse a path string, as created by PathString, into
+// path elements suitable for WalkPath.
+func ParsePathString(s string) ([]interface{}, error) {
+	path := []interface{}{}
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '[' && i+1 < len(s) && s[i+1] == '"':
+			// quoted key, find the closing quote
+			end := i + 2
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end+1 >= len(s) || s[end+1] != ']' {
+				return nil, fmt.Errorf("unterminated quoted key at offset %d in path %q", i, s)
+			}
+			key, err := strconv.Unquote(s[i+1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted key at offset %d in path %q: %w", i, s, err)
+			}
+			path = append(path, key)
+			i = end + 2
+		case s[i] == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated index at offset %d in path %q", i, s)
+			}
+			ix, err := strconv.Atoi(s[i+1 : i+end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid index at offset %d in path %q: %w", i, s, err)
+			}
+			path = append(path, ix)
+			i += end + 1
+		case s[i] == '.' && len(path) > 0:
+			i++
+			if i == len(s) || s[i] == '.' || s[i] == '[' {
+				return nil, fmt.Errorf("empty key at offset %d in path %q", i, s)
+			}
+		default:
+			end := strings.IndexAny(s[i:], ".[")
+			if end < 0 {
+				end = len(s) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key at offset %d in path %q", i, s)
+			}
+			path = append(path, s[i:i+end])
+			i += end
+		}
+	}
+	return path, nil
+}
+
+// WalkPathString is the same as WalkPath but the path is provided as a
+// string as created by PathString.
+func WalkPathString(root *yaml.Node, fn NodeFunc, path string) error {
+	elems, err := ParsePathString(path)
+	if err != nil {
+		return err
+	}
+	return WalkPath(root, fn, elems...)
+}
+
+// WalkPointer is the same as WalkPath but the path is provided as an RFC 6901
+// JSON Pointer, such as `/spec/containers/0/image`.  The `~1` and `~0` escapes
+// in each reference token are decoded to `/` and `~`.  Tokens that are array
+// indexes (`0` or digits without a leading zero) match the element at that
+// index of a sequence, or the key with that value in a mapping, all other
+// tokens only match mapping keys.  The empty pointer matches the root.  An
+// error is returned if the pointer is not empty and does not start with `/`,
+// or if it contains an invalid `~` escape.
+func WalkPointer(root *yaml.Node, fn NodeFunc, pointer string) error {
+	if pointer == "" {
+		return WalkPathMatchers(root, fn)
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+	matchers := []PathMatcher{}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		for i := 0; i < len(token); i++ {
+			if token[i] != '~' {
+				continue
+			}
+			if i+1 == len(token) || (token[i+1] != '0' && token[i+1] != '1') {
+				return fmt.Errorf("invalid JSON pointer %q: invalid escape in %q", pointer, token)
+			}
+			i++
+		}
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		matchers = append(matchers, pointerPathMatcher(token))
+	}
+	return WalkPathMatchers(root, fn, matchers...)
+}
+
+// pointerPathMatcher matches a decoded JSON Pointer reference token, as
+// described by WalkPointer.
+type pointerPathMatcher string
+
+func (pm pointerPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	token := string(pm)
+	if node.Kind != yaml.SequenceNode {
+		return matchKeys(node, func(key string) bool {
+			return key == token
+		}, fn)
+	}
+	if token == "" || len(token) > 1 && token[0] == '0' || strings.Trim(token, "0123456789") != "" {
+		return nil
+	}
+	ix, err := strconv.Atoi(token)
+	if err != nil {
+		// too large to be an index
+		return nil
+	}
+	return IndexMatcher(ix).Match(node, fn)
+}
@@ -0,0 +1,115 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEscapePathSegment(t *testing.T) {
+	for _, tt := range []struct {
+		Key     string
+		Escaped string
+	}{
+		{"simple", "simple"},
+		{"with space", "with space"},
+		{"a.b", `["a.b"]`},
+		{"a[0]", `["a[0]"]`},
+		{`say "hi"`, `["say \"hi\""]`},
+		{`back\slash`, `["back\\slash"]`},
+		{"", `[""]`},
+	} {
+		require.Equal(t, tt.Escaped, walky.EscapePathSegment(tt.Key))
+		require.Equal(t, tt.Key, walky.UnescapePathSegment(tt.Escaped))
+	}
+}
+
+func TestPathString(t *testing.T) {
+	for _, tt := range []struct {
+		Path     []interface{}
+		Expected string
+	}{
+		{[]interface{}{"spec", "containers", 0, "image"}, "spec.containers[0].image"},
+		{[]interface{}{0, 1, "a"}, "[0][1].a"},
+		{[]interface{}{"example.com", "a[1]", `q"uote`}, `["example.com"]["a[1]"]["q\"uote"]`},
+		{[]interface{}{"0", 0}, "0[0]"},
+		{[]interface{}{""}, `[""]`},
+		{[]interface{}{}, ""},
+	} {
+		got := walky.PathString(tt.Path...)
+		require.Equal(t, tt.Expected, got)
+		parsed, err := walky.ParsePathString(got)
+		require.NoError(t, err)
+		require.Equal(t, tt.Path, parsed)
+	}
+
+	for _, invalid := range []string{`a..b`, `a.`, `[0`, `["a]`, `[x]`, `.a`} {
+		_, err := walky.ParsePathString(invalid)
+		require.Error(t, err, invalid)
+	}
+
+	root := parse(t, `
+	example.com:
+		"a[1]": [x, y]
+	`)
+	path := walky.PathString("example.com", "a[1]", 1)
+	found := []string{}
+	err := walky.WalkPathString(root, func(node *yaml.Node) error {
+		found = append(found, node.Value)
+		return nil
+	}, path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"y"}, found)
+}
+
+func TestWalkPointer(t *testing.T) {
+	root := parse(t, `
+	spec:
+		containers:
+			- name: web
+			  image: web:1.0
+			- name: sidecar
+			  image: proxy:2.0
+		a/b: slash
+		m~n: tilde
+		~1: literal
+		"": empty
+		ports:
+			80: http
+			"08": leading zero
+	`)
+	get := func(pointer string) []string {
+		t.Helper()
+		got := []string{}
+		err := walky.WalkPointer(root, func(node *yaml.Node) error {
+			got = append(got, node.Value)
+			return nil
+		}, pointer)
+		require.NoError(t, err)
+		return got
+	}
+	require.Equal(t, []string{"proxy:2.0"}, get("/spec/containers/1/image"))
+	require.Equal(t, []string{"web"}, get("/spec/containers/0/name"))
+	require.Equal(t, []string{"slash"}, get("/spec/a~1b"))
+	require.Equal(t, []string{"tilde"}, get("/spec/m~0n"))
+	require.Equal(t, []string{"literal"}, get("/spec/~01"))
+	require.Equal(t, []string{"empty"}, get("/spec/"))
+	require.Equal(t, []string{"http"}, get("/spec/ports/80"))
+	require.Equal(t, []string{"leading zero"}, get("/spec/ports/08"))
+	require.Len(t, get(""), 1)
+	require.Empty(t, get("/spec/containers/2"))
+	require.Empty(t, get("/spec/containers/-"))
+	require.Empty(t, get("/spec/containers/01"))
+	require.Empty(t, get("/spec/containers/-1"))
+	require.Empty(t, get("/spec/containers/name"))
+	require.Empty(t, get("/spec/missing/0"))
+
+	err := walky.WalkPointer(root, nil, "spec/containers")
+	require.EqualError(t, err, `invalid JSON pointer "spec/containers": must start with /`)
+	err = walky.WalkPointer(root, nil, "/spec/a~2b")
+	require.EqualError(t, err, `invalid JSON pointer "/spec/a~2b": invalid escape in "a~2b"`)
+	err = walky.WalkPointer(root, nil, "/spec/a~")
+	require.EqualError(t, err, `invalid JSON pointer "/spec/a~": invalid escape in "a~"`)
+}
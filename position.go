@@ -0,0 +1,94 @@
+package walky
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lineSpan returns the first and last source lines covered by `node`,
+// including all of its content.  Aliases are not followed.
+func lineSpan(node *yaml.Node) (start, end int) {
+	start, end = node.Line, node.Line
+	if node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		// block scalars start on the line with the indicator, the
+		// content follows on the next lines.
+		end += strings.Count(strings.TrimSuffix(node.Value, "\n"), "\n") + 1
+	}
+	for _, child := range node.Content {
+		childStart, childEnd := lineSpan(child)
+		if start == 0 || (childStart > 0 && childStart < start) {
+			start = childStart
+		}
+		if childEnd > end {
+			end = childEnd
+		}
+	}
+	return start, end
+}
+
+// NodeAtLine returns the innermost node in `root` whose source span covers
+// `line`, or nil if no node covers the line.  When a mapping key and value are
+// both on the line the value is returned, when only the key is on the line
+// (ie the value is a block collection starting on the next line) the key is
+// returned.  If multiple sibling nodes are on the line (ie a flow sequence)
+// the first of them is returned.
+func NodeAtLine(root *yaml.Node, line int) *yaml.Node {
+	node := UnwrapDocument(root)
+	if start, end := lineSpan(node); line < start || line > end {
+		return nil
+	}
+	for {
+		var next *yaml.Node
+		step := 1
+		if node.Kind == yaml.MappingNode {
+			step = 2
+		}
+		for i := 0; i < len(node.Content) && next == nil; i += step {
+			candidates := node.Content[i : i+step]
+			// check the map value first so it is preferred over the key
+			for j := len(candidates) - 1; j >= 0; j-- {
+				if start, end := lineSpan(candidates[j]); line >= start && line <= end {
+					next = candidates[j]
+					break
+				}
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+}
+
+// ByteOffset returns the absolute byte offset in `source` of the start of
+// `node`, computed from the node Line and Column.  yaml.v3 counts columns in
+// characters, so multi-byte runes earlier on the line are accounted for.  The
+// returned bool is false if the node has no position or the position is not
+// within `source`.
+func ByteOffset(source []byte, node *yaml.Node) (int, bool) {
+	if node.Line < 1 || node.Column < 1 {
+		return 0, false
+	}
+	offset := 0
+	for line := 1; line < node.Line; line++ {
+		ix := bytes.IndexByte(source[offset:], '\n')
+		if ix < 0 {
+			return 0, false
+		}
+		offset += ix + 1
+	}
+	for col := 1; col < node.Column; col++ {
+		if offset >= len(source) || source[offset] == '\n' {
+			return 0, false
+		}
+		_, size := utf8.DecodeRune(source[offset:])
+		offset += size
+	}
+	if offset > len(source) {
+		return 0, false
+	}
+	return offset, true
+}
@@ -0,0 +1,90 @@
+package walky_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNodeAtLine(t *testing.T) {
+	root := parse(t, `
+	name: web
+	spec:
+		replicas: 3
+		containers:
+			- name: app
+			  image: app:1.0
+			- name: sidecar
+		script: |
+			echo one
+			echo two
+		ports: [80, 443]
+	`)
+
+	for _, tt := range []struct {
+		Line     int
+		Expected string
+	}{
+		{1, "web"},
+		{2, "spec"},
+		{3, "3"},
+		{4, "containers"},
+		{5, "app"},
+		{6, "app:1.0"},
+		{7, "sidecar"},
+		{8, "echo one\necho two\n"},
+		{9, "echo one\necho two\n"},
+		{10, "echo one\necho two\n"},
+		{11, "80"},
+	} {
+		node := walky.NodeAtLine(root, tt.Line)
+		require.NotNil(t, node, "line %d", tt.Line)
+		require.Equal(t, tt.Expected, node.Value, "line %d", tt.Line)
+	}
+
+	require.Nil(t, walky.NodeAtLine(root, 0))
+	require.Nil(t, walky.NodeAtLine(root, 12))
+}
+
+func TestByteOffset(t *testing.T) {
+	source := HereBytes(`
+	name: web
+	title: "héllo wörld" # ünïcode
+	spec: {ключ: значение, port: 80}
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(source, &root)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		path  []interface{}
+		value string
+	}{
+		{[]interface{}{"name"}, "web"},
+		{[]interface{}{"title"}, `"héllo wörld"`},
+		{[]interface{}{"spec", "ключ"}, "значение"},
+		{[]interface{}{"spec", "port"}, "80"},
+	} {
+		var node *yaml.Node
+		err := walky.WalkPath(&root, func(n *yaml.Node) error {
+			node = n
+			return nil
+		}, tt.path...)
+		require.NoError(t, err)
+		require.NotNil(t, node)
+
+		offset, ok := walky.ByteOffset(source, node)
+		require.True(t, ok)
+		require.True(t, bytes.HasPrefix(source[offset:], []byte(tt.value)), "%v at %d: %q", tt.path, offset, source[offset:])
+	}
+
+	_, ok := walky.ByteOffset(source, walky.NewStringNode("new"))
+	require.False(t, ok)
+	_, ok = walky.ByteOffset(source, &yaml.Node{Line: 10, Column: 1})
+	require.False(t, ok)
+	_, ok = walky.ByteOffset(source, &yaml.Node{Line: 1, Column: 40})
+	require.False(t, ok)
+}
@@ -0,0 +1,120 @@
+package walky
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ResolveMerges returns a new mapping node with all `!!merge` keys in
+// `mapNode` expanded into concrete key/value pairs.  Precedence follows the
+// YAML merge key spec: keys defined locally in the mapping win over merged
+// keys, and when several merge sources define the same key the source listed
+// first wins.  Merges in nested mappings are resolved as well, and aliases are
+// replaced with copies of the nodes they refer to, so the result shares no
+// nodes with `mapNode`.  An error is returned if `mapNode` is not a mapping.
+func ResolveMerges(mapNode *yaml.Node) (*yaml.Node, error) {
+	node := Indirect(mapNode)
+	if node.Kind != yaml.MappingNode {
+		return nil, NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.MappingNode), KindString(node.Kind)),
+			node,
+		)
+	}
+	return resolveNode(node, map[*yaml.Node]bool{})
+}
+
+// resolveNode returns a deep copy of `node` with all aliases replaced with
+// copies of their targets and all `!!merge` keys expanded (with the same
+// precedence as RangeMap, where duplicate keys from later merge sources are
+// dropped).  Anchors are removed from the copy.  If an alias
+// refers to a node that contains the alias (a recursive structure) the alias
+// is left in place to prevent infinite expansion.
+func resolveNode(node *yaml.Node, visiting map[*yaml.Node]bool) (*yaml.Node, error) {
+	target := node
+	for target.Kind == yaml.AliasNode && target.Alias != nil {
+		target = target.Alias
+	}
+	if visiting[target] {
+		return ShallowCopyNode(node), nil
+	}
+	visiting[target] = true
+	defer delete(visiting, target)
+
+	resolved := ShallowCopyNode(target)
+	resolved.Anchor = ""
+	resolved.Content = nil
+	if target.Kind == yaml.MappingNode {
+		seen := []*yaml.Node{}
+		err := RangeMap(target, func(key, value *yaml.Node) error {
+			for _, prev := range seen {
+				if Equal(key, prev) {
+					return nil
+				}
+			}
+			seen = append(seen, key)
+			resolvedKey, err := resolveNode(key, visiting)
+			if err != nil {
+				return err
+			}
+			resolvedValue, err := resolveNode(value, visiting)
+			if err != nil {
+				return err
+			}
+			resolved.Content = append(resolved.Content, resolvedKey, resolvedValue)
+			return nil
+		})
+		return resolved, err
+	}
+	for _, child := range target.Content {
+		resolvedChild, err := resolveNode(child, visiting)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Content = append(resolved.Content, resolvedChild)
+	}
+	return resolved, nil
+}
+
+// ResolveAliases returns a deep copy of `node` where every alias is replaced
+// with a copy of the node it refers to, and anchors are removed.  Each alias
+// gets its own copy, so nodes in the result are never shared.  Unlike
+// ResolveMerges the `!!merge` keys are left in place, with the merged mapping
+// copied inline.  If an alias refers to a node that contains the alias (a
+// recursive structure) the alias is left in place, along with the anchor on
+// the copy of the node it refers to, to prevent infinite expansion.
+func ResolveAliases(node *yaml.Node) *yaml.Node {
+	recursive := map[*yaml.Node][]*yaml.Node{}
+	return resolveAliases(node, map[*yaml.Node]bool{}, recursive)
+}
+
+// resolveAliases implements ResolveAliases, `recursive` collects the copies
+// of aliases that could not be resolved, by the node they refer to.
+func resolveAliases(node *yaml.Node, visiting map[*yaml.Node]bool, recursive map[*yaml.Node][]*yaml.Node) *yaml.Node {
+	target := node
+	for target.Kind == yaml.AliasNode && target.Alias != nil {
+		target = target.Alias
+	}
+	if visiting[target] {
+		alias := ShallowCopyNode(node)
+		recursive[target] = append(recursive[target], alias)
+		return alias
+	}
+	visiting[target] = true
+	defer delete(visiting, target)
+
+	resolved := ShallowCopyNode(target)
+	resolved.Anchor = ""
+	resolved.Content = nil
+	for _, child := range target.Content {
+		resolved.Content = append(resolved.Content, resolveAliases(child, visiting, recursive))
+	}
+	if aliases, ok := recursive[target]; ok {
+		resolved.Anchor = target.Anchor
+		for _, alias := range aliases {
+			alias.Alias = resolved
+		}
+		delete(recursive, target)
+	}
+	return resolved
+}
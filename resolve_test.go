@@ -0,0 +1,236 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestResolveMerges(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		doc      string
+		path     string
+		expected string
+	}{{
+		name: "local wins over merge",
+		doc: `
+		base: &base {a: 1, b: 2}
+		out:
+			<<: *base
+			a: local
+		`,
+		path: "out",
+		expected: `
+		b: 2
+		a: local
+		`,
+	}, {
+		name: "local wins when defined before merge",
+		doc: `
+		base: &base {a: 1, b: 2}
+		out:
+			a: local
+			<<: *base
+		`,
+		path: "out",
+		expected: `
+		a: local
+		b: 2
+		`,
+	}, {
+		name: "earlier source wins over later source",
+		doc: `
+		one: &one {a: one, b: one}
+		two: &two {b: two, c: two}
+		out:
+			<<: [*one, *two]
+		`,
+		path: "out",
+		expected: `
+		a: one
+		b: one
+		c: two
+		`,
+	}, {
+		name: "reversed sources",
+		doc: `
+		one: &one {a: one, b: one}
+		two: &two {b: two, c: two}
+		out:
+			<<: [*two, *one]
+		`,
+		path: "out",
+		expected: `
+		b: two
+		c: two
+		a: one
+		`,
+	}, {
+		name: "local beats all sources",
+		doc: `
+		one: &one {a: one, b: one}
+		two: &two {b: two, c: two}
+		out:
+			<<: [*one, *two]
+			b: local
+			c: local
+		`,
+		path: "out",
+		expected: `
+		a: one
+		b: local
+		c: local
+		`,
+	}, {
+		name: "nested merge sources",
+		doc: `
+		inner: &inner {a: inner, b: inner}
+		outer: &outer
+			<<: *inner
+			b: outer
+		out:
+			<<: *outer
+			nested:
+				<<: *inner
+				a: nested
+		`,
+		path: "out",
+		expected: `
+		a: inner
+		b: outer
+		nested:
+			b: inner
+			a: nested
+		`,
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			root := parse(t, tt.doc)
+			var mapNode *yaml.Node
+			err := walky.WalkPath(root, func(n *yaml.Node) error {
+				mapNode = n
+				return nil
+			}, tt.path)
+			require.NoError(t, err)
+			require.NotNil(t, mapNode)
+
+			got, err := walky.ResolveMerges(mapNode)
+			require.NoError(t, err)
+			out, err := yaml.Marshal(got)
+			require.NoError(t, err)
+			require.Equal(t, Here(tt.expected), string(out))
+			require.Empty(t, got.Anchor)
+		})
+	}
+
+	_, err := walky.ResolveMerges(parse(t, `[1, 2]`))
+	require.EqualError(t, err, `line 1:1: expected node kind "mapping", got "sequence"`)
+}
+
+func TestResolveMergesSelfContained(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+		ports: &ports [80, 443]
+	services:
+		web:
+			<<: *defaults
+			extra: *ports
+			sidecars:
+				- <<: *defaults
+				  name: proxy
+	`)
+	got, err := walky.ResolveMerges(root)
+	require.NoError(t, err)
+
+	err = walky.Walk(got, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		require.NotEqual(t, yaml.AliasNode, current.Kind)
+		require.NotEqual(t, "!!merge", current.Tag)
+		require.Empty(t, current.Anchor)
+		if parent != nil && parent.Kind == yaml.MappingNode {
+			require.NotEqual(t, yaml.AliasNode, parent.Content[pos+1].Kind)
+		}
+		return walky.WalkDepthFirst, nil
+	})
+	require.NoError(t, err)
+	require.True(t, walky.EqualResolved(root, got))
+
+	out, err := yaml.Marshal(got)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		defaults:
+			image: web:1.0
+			ports: [80, 443]
+		services:
+			web:
+				image: web:1.0
+				ports: [80, 443]
+				extra: [80, 443]
+				sidecars:
+					- image: web:1.0
+					  ports: [80, 443]
+					  name: proxy
+	`), string(out))
+
+	// the result shares no nodes with the original
+	walky.GetKey(walky.GetKey(got, "defaults"), "image").Value = "changed"
+	require.Equal(t, "web:1.0", walky.GetKey(walky.GetKey(root, "defaults"), "image").Value)
+}
+
+func TestResolveAliases(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: web:1.0
+	ports: &ports [80, 443]
+	web:
+		<<: *defaults
+		ports: *ports
+	api:
+		ports: *ports
+	`)
+	got := walky.ResolveAliases(root)
+	out, err := yaml.Marshal(got)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		defaults:
+			image: web:1.0
+		ports: [80, 443]
+		web:
+			!!merge <<:
+				image: web:1.0
+			ports: [80, 443]
+		api:
+			ports: [80, 443]
+	`), string(out))
+
+	// each alias gets its own copy
+	webPorts := walky.GetKey(walky.GetKey(got, "web"), "ports")
+	apiPorts := walky.GetKey(walky.GetKey(got, "api"), "ports")
+	require.NotSame(t, webPorts, apiPorts)
+	webPorts.Content[0].Value = "8080"
+	require.Equal(t, "80", apiPorts.Content[0].Value)
+	require.Equal(t, "80", walky.GetKey(walky.GetKey(root, "web"), "ports").Alias.Content[0].Value)
+	require.True(t, walky.EqualResolved(root, parse(t, string(out))))
+
+	// recursive aliases are left in place
+	recursive := parse(t, `
+	node: &node
+		name: a
+	`)
+	node := walky.GetKey(recursive, "node")
+	node.Content = append(node.Content, walky.NewStringNode("self"), &yaml.Node{Kind: yaml.AliasNode, Value: "node", Alias: node})
+	got = walky.ResolveAliases(recursive)
+	gotNode := walky.GetKey(got, "node")
+	require.NotSame(t, node, gotNode)
+	require.Equal(t, "node", gotNode.Anchor)
+	require.Same(t, gotNode, walky.GetKey(gotNode, "self").Alias)
+	out, err = yaml.Marshal(got)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		node: &node
+			name: a
+			self: *node
+	`), string(out))
+}
@@ -0,0 +1,161 @@
+package walky
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SortPaths will sort the mapping nodes found at each of the provided `paths`
+// using the SortableNodeMap ordering, leaving all other mappings in their
+// original order.  Each path is a list of elements as accepted by WalkPath.
+// An error is returned if a path matches a node that is not a mapping.
+func SortPaths(root *yaml.Node, paths ...[]interface{}) error {
+	for _, path := range paths {
+		err := WalkPath(root, func(node *yaml.Node) error {
+			node = Indirect(node)
+			if node.Kind != yaml.MappingNode {
+				return NewYAMLError(
+					fmt.Errorf("SortPaths called on invalid type: %s", KindString(node.Kind)),
+					node,
+				)
+			}
+			sort.Sort(SortableNodeMap(node))
+			return nil
+		}, path...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecursiveSort will sort the keys of every mapping in `node` in place, using
+// the SortableNodeMap ordering.  Sequences are left in their original order,
+// but mappings within them are sorted.  Aliases are followed with Indirect, and
+// each node is only visited once so recursive aliases do not loop forever.
+func RecursiveSort(node *yaml.Node) {
+	recursiveSort(node, map[*yaml.Node]bool{})
+}
+
+func recursiveSort(node *yaml.Node, visited map[*yaml.Node]bool) {
+	node = Indirect(node)
+	if visited[node] {
+		return
+	}
+	visited[node] = true
+	if node.Kind == yaml.MappingNode {
+		sort.Sort(SortableNodeMap(node))
+	}
+	for _, child := range node.Content {
+		recursiveSort(child, visited)
+	}
+}
+
+// SortSequence will sort the elements of the sequence `seq` in place, using
+// `less` to compare the elements.  The sort is stable, so equal elements keep
+// their original order.  An error is returned if `seq` is not a sequence (or
+// an alias to a sequence).
+func SortSequence(seq *yaml.Node, less func(a, b *yaml.Node) bool) error {
+	seq = Indirect(seq)
+	if seq.Kind != yaml.SequenceNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.SequenceNode), KindString(seq.Kind)),
+			seq,
+		)
+	}
+	sort.SliceStable(seq.Content, func(i, j int) bool {
+		return less(seq.Content[i], seq.Content[j])
+	})
+	return nil
+}
+
+// SortSequenceByKey will sort the mapping elements of the sequence `seq` in
+// place by the scalar value of their `key`, for example to sort a list of
+// containers by `name`.  The values are compared in the same way as the keys
+// in SortableNodeMap, so numeric values are compared numerically.  An error is
+// returned if `seq` is not a sequence, or if any element is not a mapping with
+// a scalar value for `key`, in which case the sequence is not modified.
+func SortSequenceByKey(seq *yaml.Node, key string) error {
+	seq = Indirect(seq)
+	if seq.Kind != yaml.SequenceNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.SequenceNode), KindString(seq.Kind)),
+			seq,
+		)
+	}
+	values := map[*yaml.Node]*yaml.Node{}
+	for _, elem := range seq.Content {
+		if Indirect(elem).Kind != yaml.MappingNode {
+			return NewYAMLError(
+				fmt.Errorf("expected node kind %q, got %q", KindString(yaml.MappingNode), KindString(Indirect(elem).Kind)),
+				elem,
+			)
+		}
+		value := GetKey(Indirect(elem), key)
+		if value == nil {
+			return Errorf(elem, "missing key %q", key)
+		}
+		if value = Indirect(value); value.Kind != yaml.ScalarNode {
+			return NewYAMLError(
+				fmt.Errorf("expected node kind %q, got %q", KindString(yaml.ScalarNode), KindString(value.Kind)),
+				value,
+			)
+		}
+		values[elem] = value
+	}
+	return SortSequence(seq, func(a, b *yaml.Node) bool {
+		return sortableNodeMap{values[a], nil, values[b], nil}.Less(0, 1)
+	})
+}
+
+// SortKey returns a canonical string derived from the resolved content of
+// `node`, suitable for use as a sort key to give a stable total order over
+// nodes of mixed kinds.  Aliases and `!!merge` keys are resolved first, map
+// entries are ordered by their own sort keys, and comments, styles and
+// positions are ignored, so nodes that are Equal will produce the same key.
+// Scalars sort before sequences which sort before mappings.
+func SortKey(node *yaml.Node) string {
+	resolved, err := resolveNode(UnwrapDocument(node), map[*yaml.Node]bool{})
+	if err != nil {
+		// malformed mappings cannot be resolved, fall back to the
+		// unresolved content.
+		resolved = UnwrapDocument(node)
+	}
+	var buf strings.Builder
+	writeSortKey(&buf, resolved)
+	return buf.String()
+}
+
+func writeSortKey(buf *strings.Builder, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		buf.WriteString("0" + node.ShortTag() + ":" + strconv.Quote(node.Value))
+	case yaml.SequenceNode:
+		buf.WriteString("1" + node.ShortTag() + "[")
+		for i, child := range node.Content {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			writeSortKey(buf, child)
+		}
+		buf.WriteString("]")
+	case yaml.MappingNode:
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			var pair strings.Builder
+			writeSortKey(&pair, node.Content[i])
+			pair.WriteString("=")
+			writeSortKey(&pair, node.Content[i+1])
+			pairs = append(pairs, pair.String())
+		}
+		sort.Strings(pairs)
+		buf.WriteString("2" + node.ShortTag() + "{" + strings.Join(pairs, ",") + "}")
+	default:
+		// only unresolvable (recursive) aliases remain
+		buf.WriteString("3*" + strconv.Quote(node.Value))
+	}
+}
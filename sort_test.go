@@ -0,0 +1,203 @@
+package walky_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSortPaths(t *testing.T) {
+	doc := HereBytes(`
+	metadata:
+		name: test
+		labels:
+			tier: web
+			app: demo
+			env: prod
+		annotations:
+			zeta: z
+			alpha: a
+	spec:
+		replicas: 3
+		image: demo
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	err = walky.SortPaths(&root, []interface{}{"metadata", "labels"})
+	require.NoError(t, err)
+
+	got, err := yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		metadata:
+			name: test
+			labels:
+				app: demo
+				env: prod
+				tier: web
+			annotations:
+				zeta: z
+				alpha: a
+		spec:
+			replicas: 3
+			image: demo
+	`), string(got))
+
+	err = walky.SortPaths(&root, []interface{}{"metadata", "name"})
+	require.EqualError(t, err, `line 2:11 at "test": SortPaths called on invalid type: scalar`)
+}
+
+func TestSortKey(t *testing.T) {
+	root := parse(t, `
+	base: &base {b: 2, a: 1}
+	items:
+		- {a: 1, b: 2}
+		- [1, 2]
+		- "10"
+		- 9
+		- b: 2
+		  a: 1
+		- *base
+		- <<: *base
+		- true
+		- [1]
+	`)
+	var items *yaml.Node
+	err := walky.WalkPath(root, func(n *yaml.Node) error {
+		items = n
+		return nil
+	}, "items")
+	require.NoError(t, err)
+
+	keys := []string{}
+	for _, item := range items.Content {
+		keys = append(keys, walky.SortKey(item))
+	}
+	// all of the maps are equal regardless of key order, aliases or merges
+	require.Equal(t, keys[0], keys[4])
+	require.Equal(t, keys[0], keys[5])
+	require.Equal(t, keys[0], keys[6])
+	// tags are significant
+	require.NotEqual(t, keys[2], walky.SortKey(walky.NewStringNode("9")))
+	require.NotEqual(t, keys[3], walky.SortKey(walky.NewStringNode("9")))
+	require.Equal(t, keys[3], walky.SortKey(walky.NewIntNode(9)))
+
+	sorted := func() string {
+		content := append([]*yaml.Node{}, items.Content...)
+		sort.SliceStable(content, func(i, j int) bool {
+			return walky.SortKey(content[i]) < walky.SortKey(content[j])
+		})
+		seq := walky.NewSequenceNode()
+		seq.Content = content
+		seq.Style = yaml.FlowStyle
+		out, err := yaml.Marshal(seq)
+		require.NoError(t, err)
+		return string(out)
+	}
+	expected := sorted()
+	require.Equal(t, "[true, 9, \"10\", [1, 2], [1], {a: 1, b: 2}, {b: 2, a: 1}, *base, {!!merge <<: *base}]\n", expected)
+	for i := 0; i < 5; i++ {
+		require.Equal(t, expected, sorted())
+	}
+}
+
+func TestRecursiveSort(t *testing.T) {
+	root := parse(t, `
+	zeta: 1
+	defaults: &defaults
+		timeout: 30
+		retries: 3
+	services:
+		- name: web
+		  image: web:1.0
+		  <<: *defaults
+		- [c, b, a]
+	alpha:
+		self: &self
+			y: 1
+			x: 1
+	`)
+	// make a recursive alias to ensure we do not loop forever
+	self := walky.GetKey(walky.GetKey(root, "alpha"), "self")
+	self.Content = append(self.Content, walky.NewStringNode("loop"), &yaml.Node{Kind: yaml.AliasNode, Value: "self", Alias: self})
+
+	walky.RecursiveSort(root)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		alpha:
+			self: &self
+				loop: *self
+				x: 1
+				y: 1
+		defaults: &defaults
+			retries: 3
+			timeout: 30
+		services:
+			- !!merge <<: *defaults
+			  image: web:1.0
+			  name: web
+			- [c, b, a]
+		zeta: 1
+	`), string(got))
+}
+
+func TestSortSequence(t *testing.T) {
+	root := parse(t, `
+	ports: [443, 80, 8080, 22]
+	containers:
+		- name: web
+		  port: 80
+		- name: api
+		  port: 8080
+		- name: db
+		  port: 5432
+		- name: cache
+		  port: 6379
+	`)
+	err := walky.SortSequence(walky.GetKey(root, "ports"), func(a, b *yaml.Node) bool {
+		return len(a.Value) < len(b.Value)
+	})
+	require.NoError(t, err)
+
+	err = walky.SortSequenceByKey(walky.GetKey(root, "containers"), "name")
+	require.NoError(t, err)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		ports: [80, 22, 443, 8080]
+		containers:
+			- name: api
+			  port: 8080
+			- name: cache
+			  port: 6379
+			- name: db
+			  port: 5432
+			- name: web
+			  port: 80
+	`), string(got))
+
+	// numeric values are compared numerically
+	err = walky.SortSequenceByKey(walky.GetKey(root, "containers"), "port")
+	require.NoError(t, err)
+	names := []string{}
+	for _, elem := range walky.GetKey(root, "containers").Content {
+		names = append(names, walky.GetKey(elem, "name").Value)
+	}
+	require.Equal(t, []string{"web", "db", "cache", "api"}, names)
+
+	err = walky.SortSequence(walky.GetKey(root, "containers").Content[0], nil)
+	require.EqualError(t, err, `line 3:7: expected node kind "sequence", got "mapping"`)
+
+	err = walky.SortSequenceByKey(walky.GetKey(root, "ports"), "name")
+	require.EqualError(t, err, `line 1:14 at "80": expected node kind "mapping", got "scalar"`)
+
+	err = walky.SortSequenceByKey(walky.GetKey(root, "containers"), "image")
+	require.EqualError(t, err, `line 3:7: missing key "image"`)
+}
@@ -0,0 +1,108 @@
+package walky
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ApplyParams will replace every node in `root` tagged with `!param` with a
+// copy of the node from `params` named by the tagged node value.  For example
+// with the template:
+//
+//	name: !param appName
+//	replicas: !param count
+//
+// and params `{"appName": NewStringNode("web"), "count": NewIntNode(3)}` the
+// result will be:
+//
+//	name: web
+//	replicas: 3
+//
+// Comments on the template nodes are preserved.  A YAMLErrors list is returned
+// for any `!param` that does not have a value in `params` and an error is
+// returned if any of the `params` are not used in the template.  The template
+// is not modified if an error is returned.
+func ApplyParams(root *yaml.Node, params map[string]*yaml.Node) error {
+	placeholders := []*yaml.Node{}
+	used := map[string]bool{}
+	errs := YAMLErrors{}
+	check := func(node *yaml.Node) error {
+		if node.Kind != yaml.ScalarNode || node.Tag != "!param" {
+			return nil
+		}
+		if _, ok := params[node.Value]; !ok {
+			errs = append(errs, NewYAMLError(
+				fmt.Errorf("missing value for param %q", node.Value),
+				node,
+			).(YAMLError))
+			return nil
+		}
+		used[node.Value] = true
+		placeholders = append(placeholders, node)
+		return nil
+	}
+	err := Walk(root, allNodesWalker(check))
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	unknown := []string{}
+	for name := range params {
+		if !used[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown params: %s", strings.Join(unknown, ", "))
+	}
+	for _, node := range placeholders {
+		AssignNode(node, CopyNode(UnwrapDocument(params[node.Value])))
+		// the `!param` tag was explicit, so make sure we don't
+		// explicitly emit the tag of the replacement value.
+		node.Style &^= yaml.TaggedStyle
+	}
+	return nil
+}
+
+// NodeFromTemplate executes the text/template `tmpl` with `data` and parses
+// the rendered output as a YAML document, returning the document content.
+// For example:
+//
+//	node, err := NodeFromTemplate(`
+//	name: {{ .Name }}
+//	replicas: {{ .Replicas }}
+//	`, data)
+//
+// Errors from parsing or executing the template are returned unmodified from
+// the text/template package, while errors parsing the rendered YAML are
+// returned as a YAMLError.  If the template renders an empty document a
+// `!!null` node is returned.
+func NodeFromTemplate(tmpl string, data interface{}) (*yaml.Node, error) {
+	t, err := template.New("node").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		if ye, ok := ErrDecode(err).(YAMLError); ok {
+			return nil, ye
+		}
+		return nil, YAMLError{Err: err}
+	}
+	if doc.Kind == 0 {
+		return NewNullNode(), nil
+	}
+	return UnwrapDocument(&doc), nil
+}
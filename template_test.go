@@ -0,0 +1,113 @@
+package walky_test
+
+import (
+	"errors"
+	"testing"
+	"text/template"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestApplyParams(t *testing.T) {
+	doc := HereBytes(`
+	name: !param appName # the app
+	spec:
+		replicas: !param count
+		ports:
+			- !param port
+			- 443
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	ports, err := walky.ToNode(map[string]int{"http": 80})
+	require.NoError(t, err)
+	params := map[string]*yaml.Node{
+		"appName": walky.NewStringNode("web"),
+		"count":   walky.NewIntNode(3),
+		"port":    ports,
+	}
+
+	err = walky.ApplyParams(&root, map[string]*yaml.Node{
+		"appName": params["appName"],
+	})
+	require.EqualError(t, err, Here(`
+		line 3:15 at "count": missing value for param "count"
+		line 5:11 at "port": missing value for param "port"`))
+
+	err = walky.ApplyParams(&root, map[string]*yaml.Node{
+		"appName": params["appName"],
+		"count":   params["count"],
+		"port":    params["port"],
+		"extra":   params["count"],
+	})
+	require.EqualError(t, err, "unknown params: extra")
+
+	// template is unchanged after errors
+	got, err := yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, string(doc), string(got))
+
+	err = walky.ApplyParams(&root, params)
+	require.NoError(t, err)
+	got, err = yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web # the app
+		spec:
+			replicas: 3
+			ports:
+				- http: 80
+				- 443
+	`), string(got))
+}
+
+func TestNodeFromTemplate(t *testing.T) {
+	data := map[string]interface{}{
+		"Name":     "web",
+		"Replicas": 3,
+		"Ports":    []int{80, 443},
+	}
+	node, err := walky.NodeFromTemplate(Here(`
+		name: {{ .Name }}
+		replicas: {{ .Replicas }}
+		ports:
+		{{- range .Ports }}
+			- {{ . }}
+		{{- end }}
+	`), data)
+	require.NoError(t, err)
+	require.Equal(t, yaml.MappingNode, node.Kind)
+	require.Equal(t, "web", walky.GetKey(node, "name").Value)
+	require.Equal(t, "!!int", walky.GetKey(node, "replicas").ShortTag())
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		replicas: 3
+		ports:
+			- 80
+			- 443
+	`), string(got))
+
+	node, err = walky.NodeFromTemplate(`{{ if .Missing }}key: value{{ end }}`, data)
+	require.NoError(t, err)
+	require.True(t, walky.IsNull(node))
+
+	// template errors
+	_, err = walky.NodeFromTemplate(`{{ .Name `, data)
+	require.Error(t, err)
+	require.False(t, errors.As(err, &walky.YAMLError{}))
+	_, err = walky.NodeFromTemplate(`{{ .Name.Foo }}`, data)
+	require.Error(t, err)
+	var execErr template.ExecError
+	require.True(t, errors.As(err, &execErr))
+
+	// yaml errors
+	_, err = walky.NodeFromTemplate("name: {{ .Name }}\n  bad: [", data)
+	require.Error(t, err)
+	require.True(t, errors.As(err, &walky.YAMLError{}))
+}
@@ -0,0 +1,197 @@
+package walky
+
+import (
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TokenType is the type of a Token returned by Tokens.
+type TokenType int
+
+const (
+	// TokenKey is a scalar map key.
+	TokenKey TokenType = iota
+	// TokenString is a string scalar value (or any scalar with an
+	// unrecognized tag).
+	TokenString
+	// TokenNumber is an `!!int` or `!!float` scalar value.
+	TokenNumber
+	// TokenBool is a `!!bool` scalar value.
+	TokenBool
+	// TokenNull is a `!!null` scalar value.
+	TokenNull
+	// TokenComment is a head, line or foot comment, including the `#`.
+	TokenComment
+	// TokenPunctuation is one of the indicators `:`, `-`, `,`, `[`, `]`,
+	// `{` or `}`.
+	TokenPunctuation
+	// TokenAnchor is an anchor (`&name`) or alias (`*name`).
+	TokenAnchor
+	// TokenTag is an explicit tag, such as `!!str`.
+	TokenTag
+)
+
+func (t TokenType) String() string {
+	switch t {
+	case TokenKey:
+		return "Key"
+	case TokenString:
+		return "String"
+	case TokenNumber:
+		return "Number"
+	case TokenBool:
+		return "Bool"
+	case TokenNull:
+		return "Null"
+	case TokenComment:
+		return "Comment"
+	case TokenPunctuation:
+		return "Punctuation"
+	case TokenAnchor:
+		return "Anchor"
+	case TokenTag:
+		return "Tag"
+	default:
+		return "Invalid"
+	}
+}
+
+// Token is a single syntax element returned by Tokens.
+type Token struct {
+	Type TokenType
+	// Text is the token text.  For scalars this is the node Value, so
+	// quotes and block indicators are not included.
+	Text string
+	// Line and Column are the position of the node the token was derived
+	// from, and will be 0 for nodes that were not decoded from a document.
+	// Nodes have no position for punctuation or comments, so those are
+	// approximated from the nearest node.
+	Line   int
+	Column int
+}
+
+// Tokens returns the syntax tokens for `root`, derived from the node tree and
+// comments, in document order.  This is intended for applying syntax
+// highlighting, for example in a TUI, without needing a YAML lexer.  Comments
+// are emitted as a single token each, even if they span multiple lines.
+func Tokens(root *yaml.Node) []Token {
+	var tokens []Token
+	emitAt := func(typ TokenType, text string, line, column int) {
+		tokens = append(tokens, Token{Type: typ, Text: text, Line: line, Column: column})
+	}
+	emit := func(typ TokenType, text string, node *yaml.Node) {
+		emitAt(typ, text, node.Line, node.Column)
+	}
+	// colon emits the `:` following `key`, which directly follows plain
+	// keys.
+	colon := func(key *yaml.Node) {
+		column := key.Column
+		if key.Kind == yaml.ScalarNode && key.Style == 0 && column > 0 {
+			column += utf8.RuneCountInString(key.Value)
+		}
+		emitAt(TokenPunctuation, ":", key.Line, column)
+	}
+	comment := func(text string, node *yaml.Node) {
+		if text != "" {
+			emit(TokenComment, text, node)
+		}
+	}
+	var visit func(node *yaml.Node, isKey, inFlow bool)
+	visit = func(node *yaml.Node, isKey, inFlow bool) {
+		if node.Anchor != "" {
+			emit(TokenAnchor, "&"+node.Anchor, node)
+		}
+		if node.Style&yaml.TaggedStyle != 0 && node.Tag != "" {
+			emit(TokenTag, node.Tag, node)
+		}
+		flow := inFlow || node.Style&yaml.FlowStyle != 0
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				comment(child.HeadComment, child)
+				visit(child, false, false)
+				comment(child.LineComment, child)
+				comment(child.FootComment, child)
+			}
+		case yaml.AliasNode:
+			emit(TokenAnchor, "*"+node.Value, node)
+		case yaml.ScalarNode:
+			emit(scalarTokenType(node, isKey), node.Value, node)
+		case yaml.SequenceNode:
+			if flow {
+				emit(TokenPunctuation, "[", node)
+			}
+			for i, child := range node.Content {
+				if flow {
+					if i > 0 {
+						emit(TokenPunctuation, ",", child)
+					}
+					visit(child, false, true)
+					continue
+				}
+				comment(child.HeadComment, child)
+				column := child.Column
+				if column > 2 {
+					// the element follows the `- ` indicator
+					column -= 2
+				}
+				emitAt(TokenPunctuation, "-", child.Line, column)
+				visit(child, false, false)
+				comment(child.LineComment, child)
+				comment(child.FootComment, child)
+			}
+			if flow {
+				emit(TokenPunctuation, "]", node)
+			}
+		case yaml.MappingNode:
+			if flow {
+				emit(TokenPunctuation, "{", node)
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, value := node.Content[i], node.Content[i+1]
+				if flow {
+					if i > 0 {
+						emit(TokenPunctuation, ",", key)
+					}
+					visit(key, true, true)
+					colon(key)
+					visit(value, false, true)
+					continue
+				}
+				comment(key.HeadComment, key)
+				visit(key, true, false)
+				colon(key)
+				comment(key.LineComment, key)
+				comment(value.HeadComment, value)
+				visit(value, false, false)
+				comment(value.LineComment, value)
+				comment(value.FootComment, value)
+				comment(key.FootComment, key)
+			}
+			if flow {
+				emit(TokenPunctuation, "}", node)
+			}
+		}
+	}
+	comment(root.HeadComment, root)
+	visit(root, false, false)
+	comment(root.LineComment, root)
+	comment(root.FootComment, root)
+	return tokens
+}
+
+func scalarTokenType(node *yaml.Node, isKey bool) TokenType {
+	if isKey {
+		return TokenKey
+	}
+	switch node.ShortTag() {
+	case "!!int", "!!float":
+		return TokenNumber
+	case "!!bool":
+		return TokenBool
+	case "!!null":
+		return TokenNull
+	}
+	return TokenString
+}
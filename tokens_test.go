@@ -0,0 +1,64 @@
+package walky_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokens(t *testing.T) {
+	root := parse(t, `
+	# config
+	name: web # the name
+	replicas: 3
+	enabled: true
+	base: &base
+		ratio: 0.5
+	other: *base
+	ports:
+		- 80
+		- ~
+	tags: [a, "b"]
+	`)
+	got := []string{}
+	for _, tok := range walky.Tokens(root) {
+		got = append(got, fmt.Sprintf("%d:%d %s %q", tok.Line, tok.Column, tok.Type, tok.Text))
+	}
+	require.Equal(t, []string{
+		`2:1 Comment "# config"`,
+		`2:1 Key "name"`,
+		`2:5 Punctuation ":"`,
+		`2:7 String "web"`,
+		`2:7 Comment "# the name"`,
+		`3:1 Key "replicas"`,
+		`3:9 Punctuation ":"`,
+		`3:11 Number "3"`,
+		`4:1 Key "enabled"`,
+		`4:8 Punctuation ":"`,
+		`4:10 Bool "true"`,
+		`5:1 Key "base"`,
+		`5:5 Punctuation ":"`,
+		`5:7 Anchor "&base"`,
+		`6:5 Key "ratio"`,
+		`6:10 Punctuation ":"`,
+		`6:12 Number "0.5"`,
+		`7:1 Key "other"`,
+		`7:6 Punctuation ":"`,
+		`7:8 Anchor "*base"`,
+		`8:1 Key "ports"`,
+		`8:6 Punctuation ":"`,
+		`9:5 Punctuation "-"`,
+		`9:7 Number "80"`,
+		`10:5 Punctuation "-"`,
+		`10:7 Null "~"`,
+		`11:1 Key "tags"`,
+		`11:5 Punctuation ":"`,
+		`11:7 Punctuation "["`,
+		`11:8 String "a"`,
+		`11:11 Punctuation ","`,
+		`11:11 String "b"`,
+		`11:7 Punctuation "]"`,
+	}, got)
+}
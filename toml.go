@@ -0,0 +1,221 @@
+package walky
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MarshalTOML will convert the node tree to a TOML document.  Aliases and
+// `!!merge` keys are expanded first.  The root node must be a mapping, nested
+// mappings are written as tables, sequences where every element is a mapping
+// are written as arrays of tables and all other sequences are written as
+// inline arrays (with any mappings as inline tables).  Scalars are converted
+// according to their tag.  TOML has no representation for null values, so a
+// YAMLError is returned if a null is found, likewise for non scalar mapping
+// keys.
+func MarshalTOML(root *yaml.Node) ([]byte, error) {
+	resolved, err := resolveNode(UnwrapDocument(root), map[*yaml.Node]bool{})
+	if err != nil {
+		return nil, err
+	}
+	if resolved.Kind != yaml.MappingNode {
+		return nil, Errorf(resolved, "TOML document must be a mapping, got %s", KindString(resolved.Kind))
+	}
+	var buf bytes.Buffer
+	if err := writeTOMLTable(&buf, nil, resolved, false); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// isTOMLArrayOfTables returns true if the node is a non-empty sequence
+// containing only mappings.
+func isTOMLArrayOfTables(node *yaml.Node) bool {
+	if node.Kind != yaml.SequenceNode || len(node.Content) == 0 {
+		return false
+	}
+	for _, elem := range node.Content {
+		if elem.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return true
+}
+
+func writeTOMLTable(buf *bytes.Buffer, path []string, node *yaml.Node, arrayElem bool) error {
+	if len(path) > 0 {
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		if arrayElem {
+			buf.WriteString("[[" + strings.Join(path, ".") + "]]\n")
+		} else {
+			buf.WriteString("[" + strings.Join(path, ".") + "]\n")
+		}
+	}
+	type entry struct {
+		key   string
+		value *yaml.Node
+	}
+	tables, arrays := []entry{}, []entry{}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, err := tomlKey(node.Content[i])
+		if err != nil {
+			return err
+		}
+		value := node.Content[i+1]
+		switch {
+		case value.Kind == yaml.MappingNode:
+			tables = append(tables, entry{key, value})
+		case isTOMLArrayOfTables(value):
+			arrays = append(arrays, entry{key, value})
+		default:
+			inline, err := tomlInline(value)
+			if err != nil {
+				return err
+			}
+			buf.WriteString(key + " = " + inline + "\n")
+		}
+	}
+	for _, e := range tables {
+		if err := writeTOMLTable(buf, append(path[:len(path):len(path)], e.key), e.value, false); err != nil {
+			return err
+		}
+	}
+	for _, e := range arrays {
+		for _, elem := range e.value.Content {
+			if err := writeTOMLTable(buf, append(path[:len(path):len(path)], e.key), elem, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(node *yaml.Node) (string, error) {
+	if node.Kind != yaml.ScalarNode {
+		return "", Errorf(node, "TOML keys must be scalars, got %s", KindString(node.Kind))
+	}
+	if tomlBareKey.MatchString(node.Value) {
+		return node.Value, nil
+	}
+	return tomlString(node.Value), nil
+}
+
+func tomlInline(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return tomlScalar(node)
+	case yaml.SequenceNode:
+		elems := make([]string, 0, len(node.Content))
+		for _, elem := range node.Content {
+			inline, err := tomlInline(elem)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, inline)
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case yaml.MappingNode:
+		if len(node.Content) == 0 {
+			return "{}", nil
+		}
+		pairs := make([]string, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			key, err := tomlKey(node.Content[i])
+			if err != nil {
+				return "", err
+			}
+			inline, err := tomlInline(node.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, key+" = "+inline)
+		}
+		return "{ " + strings.Join(pairs, ", ") + " }", nil
+	}
+	return "", Errorf(node, "unable to convert %s to TOML", KindString(node.Kind))
+}
+
+func tomlScalar(node *yaml.Node) (string, error) {
+	if node.ShortTag() == "!!null" {
+		return "", Errorf(node, "TOML cannot represent null values")
+	}
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", NewYAMLError(err, node)
+	}
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return "", Errorf(node, "integer %d overflows TOML integer", v)
+		}
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		case math.IsNaN(v):
+			return "nan", nil
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case string:
+		return tomlString(v), nil
+	}
+	return tomlString(node.Value), nil
+}
+
+// tomlString returns `s` as a TOML basic string.
+func tomlString(s string) string {
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\r':
+			buf.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&buf, `\u%04X`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
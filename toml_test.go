@@ -0,0 +1,71 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalTOML(t *testing.T) {
+	root := parse(t, `
+	title: "TOML \"Example\""
+	enabled: true
+	ratio: 0.5
+	whole: 3.0
+	count: 0x10
+	created: 2001-12-14T21:59:43Z
+	defaults: &defaults
+		timeout: 30
+	database:
+		<<: *defaults
+		ports: [8000, 8001]
+		dotted.key: value
+		connection:
+			retries: 3
+	servers:
+		- name: alpha
+		  ip: 10.0.0.1
+		- name: beta
+		  tags: [a, {b: 1}]
+	`)
+
+	got, err := walky.MarshalTOML(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		title = "TOML \"Example\""
+		enabled = true
+		ratio = 0.5
+		whole = 3.0
+		count = 16
+		created = 2001-12-14T21:59:43Z
+
+		[defaults]
+		timeout = 30
+
+		[database]
+		timeout = 30
+		ports = [8000, 8001]
+		"dotted.key" = "value"
+
+		[database.connection]
+		retries = 3
+
+		[[servers]]
+		name = "alpha"
+		ip = "10.0.0.1"
+
+		[[servers]]
+		name = "beta"
+		tags = ["a", { b = 1 }]
+	`), string(got))
+
+	_, err = walky.MarshalTOML(parse(t, `[1, 2]`))
+	require.EqualError(t, err, "line 1:1: TOML document must be a mapping, got sequence")
+
+	_, err = walky.MarshalTOML(parse(t, `
+	a:
+		b: ~
+	`))
+	require.EqualError(t, err, `line 2:8 at "~": TOML cannot represent null values`)
+}
@@ -0,0 +1,369 @@
+package walky
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MapKeysToLower will rewrite all scalar map keys in `root` to lower case.  If
+// two keys within the same map would collide after conversion a YAMLErrors list
+// is returned and `root` is not modified.
+func MapKeysToLower(root *yaml.Node) error {
+	return mapKeysCase(root, strings.ToLower)
+}
+
+// MapKeysToUpper will rewrite all scalar map keys in `root` to upper case.  If
+// two keys within the same map would collide after conversion a YAMLErrors list
+// is returned and `root` is not modified.
+func MapKeysToUpper(root *yaml.Node) error {
+	return mapKeysCase(root, strings.ToUpper)
+}
+
+func mapKeysCase(root *yaml.Node, convert func(string) string) error {
+	keys := []*yaml.Node{}
+	seen := map[*yaml.Node]map[string]*yaml.Node{}
+	errs := YAMLErrors{}
+	err := Walk(root, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if parent == nil || parent.Kind != yaml.MappingNode || current.Kind != yaml.ScalarNode {
+			return opts.MissStatus(), nil
+		}
+		if seen[parent] == nil {
+			seen[parent] = map[string]*yaml.Node{}
+		}
+		converted := convert(current.Value)
+		if prev, ok := seen[parent][converted]; ok {
+			errs = append(errs, NewYAMLError(
+				fmt.Errorf("key collides with %q from line %d as %q", prev.Value, prev.Line, converted),
+				current,
+			).(YAMLError))
+		}
+		seen[parent][converted] = current
+		keys = append(keys, current)
+		return opts.MissStatus(), nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	for _, key := range keys {
+		key.Value = convert(key.Value)
+	}
+	return nil
+}
+
+// Project returns a copy of `root` containing only the nodes matched by each
+// of the `keepPaths` (along with everything below them).  Each path is a list
+// of elements as accepted by WalkPath.  Mappings and sequences are only
+// retained in the copy if they are needed to reach a matched node, all other
+// content is dropped.  Note that sequences are compacted, so retained sequence
+// elements may have a different index in the projected document.
+func Project(root *yaml.Node, keepPaths ...[]interface{}) (*yaml.Node, error) {
+	projected := CopyNode(root)
+	keep := map[*yaml.Node]bool{}
+	for _, path := range keepPaths {
+		err := WalkPath(projected, func(node *yaml.Node) error {
+			keep[node] = true
+			return nil
+		}, path...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	prune(UnwrapDocument(projected), keep)
+	return projected, nil
+}
+
+// prune will remove all content from `node` that does not lead to a node
+// in `keep`.  It returns true if `node` should be retained by its parent.
+func prune(node *yaml.Node, keep map[*yaml.Node]bool) bool {
+	if keep[node] {
+		return true
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		content := []*yaml.Node{}
+		for i := 0; i < len(node.Content); i += 2 {
+			if prune(node.Content[i+1], keep) {
+				content = append(content, node.Content[i], node.Content[i+1])
+			}
+		}
+		node.Content = content
+	case yaml.SequenceNode:
+		content := []*yaml.Node{}
+		for _, elem := range node.Content {
+			if prune(elem, keep) {
+				content = append(content, elem)
+			}
+		}
+		node.Content = content
+	default:
+		return false
+	}
+	return len(node.Content) > 0
+}
+
+const (
+	// EmptyFlowMap is used with NormalizeEmpties to represent empty mappings
+	// as `{}`.
+	EmptyFlowMap = "{}"
+	// EmptyFlowSeq is used with NormalizeEmpties to represent empty sequences
+	// as `[]`.
+	EmptyFlowSeq = "[]"
+	// EmptyBlock is used with NormalizeEmpties to represent empty mappings or
+	// sequences in block form.  YAML has no syntax for an empty block
+	// collection, so the node is converted to an implicit null (ie `key:`).
+	EmptyBlock = "block"
+)
+
+// NormalizeEmpties will rewrite all empty mappings in `root` to the
+// `mapForm` representation and all empty sequences to the `seqForm`
+// representation.  `mapForm` must be one of EmptyFlowMap or EmptyBlock and
+// `seqForm` must be one of EmptyFlowSeq or EmptyBlock.  An empty form will
+// leave the corresponding containers unchanged.
+func NormalizeEmpties(root *yaml.Node, mapForm, seqForm string) error {
+	if mapForm != "" && mapForm != EmptyFlowMap && mapForm != EmptyBlock {
+		return fmt.Errorf("invalid empty mapping form %q", mapForm)
+	}
+	if seqForm != "" && seqForm != EmptyFlowSeq && seqForm != EmptyBlock {
+		return fmt.Errorf("invalid empty sequence form %q", seqForm)
+	}
+	empties := []*yaml.Node{}
+	err := Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if (node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode) && len(node.Content) == 0 {
+			empties = append(empties, node)
+		}
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+	for _, node := range empties {
+		form := mapForm
+		if node.Kind == yaml.SequenceNode {
+			form = seqForm
+		}
+		switch form {
+		case EmptyFlowMap, EmptyFlowSeq:
+			node.Style |= yaml.FlowStyle
+		case EmptyBlock:
+			node.Kind = yaml.ScalarNode
+			node.Tag = "!!null"
+			node.Value = ""
+			node.Content = nil
+			node.Style = 0
+		}
+	}
+	return nil
+}
+
+// scalarsEqualTo returns every scalar node in `root` (including map keys)
+// that is Equal to `val` converted with ToNode.  Aliases are not followed, so
+// an anchored scalar is only returned once.
+func scalarsEqualTo(root *yaml.Node, val interface{}) ([]*yaml.Node, error) {
+	target, err := ToNode(val)
+	if err != nil {
+		return nil, err
+	}
+	matches := []*yaml.Node{}
+	err = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if node.Kind == yaml.ScalarNode && Equal(node, target) {
+			matches = append(matches, node)
+		}
+		return nil
+	}))
+	return matches, err
+}
+
+// CountValue returns the number of scalar nodes in `root` (including map
+// keys) that are Equal to `val` converted with ToNode.  Aliases are not
+// followed, so an anchored scalar is only counted once.  If `val` cannot be
+// converted then 0 is returned.
+func CountValue(root *yaml.Node, val interface{}) int {
+	matches, err := scalarsEqualTo(root, val)
+	if err != nil {
+		return 0
+	}
+	return len(matches)
+}
+
+// ReplaceValue will replace every scalar node in `root` (including map keys)
+// that is Equal to `old` with a node created from `new` via ToNode.  The
+// replacement uses AssignNode, so comments and style of the replaced nodes are
+// preserved, unless the replacement has a different tag in which case the
+// style is reset (so a quoted string replaced by an int is not emitted as a
+// string).  Anchors on the replaced nodes are kept, so aliases referencing
+// them are still valid.  The number of replaced nodes is returned, if either value cannot
+// be converted with ToNode then nothing is replaced and 0 is returned.
+func ReplaceValue(root *yaml.Node, old, new interface{}) int {
+	replacement, err := ToNode(new)
+	if err != nil {
+		return 0
+	}
+	matches, err := scalarsEqualTo(root, old)
+	if err != nil {
+		return 0
+	}
+	for _, node := range matches {
+		if node.ShortTag() != replacement.ShortTag() {
+			node.Style = 0
+		}
+		// keep the anchor so aliases to the node still resolve
+		anchor := node.Anchor
+		AssignNode(node, CopyNode(replacement))
+		node.Anchor = anchor
+	}
+	return len(matches)
+}
+
+// TabsInScalars returns every scalar node in `root` (including map keys)
+// whose value contains a tab character, in document order.
+func TabsInScalars(root *yaml.Node) []*yaml.Node {
+	found := []*yaml.Node{}
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "\t") {
+			found = append(found, node)
+		}
+		return nil
+	}))
+	return found
+}
+
+// ReplaceTabsInScalars will replace each tab character in the scalar values of
+// `root` (including map keys) with `spaces` space characters.  The number of
+// modified nodes is returned.
+func ReplaceTabsInScalars(root *yaml.Node, spaces int) int {
+	nodes := TabsInScalars(root)
+	for _, node := range nodes {
+		node.Value = strings.ReplaceAll(node.Value, "\t", strings.Repeat(" ", spaces))
+	}
+	return len(nodes)
+}
+
+// MigrateKeys will rename mapping keys throughout `root` according to
+// `renames`, which maps old key names to new key names.  Only the key value
+// is changed, so the value, comments and position of the entry are preserved.
+// If a map already contains the new key name then the old key is left
+// unchanged to avoid creating duplicate keys.  The number of renamed keys is
+// returned.
+func MigrateKeys(root *yaml.Node, renames map[string]string) int {
+	count := 0
+	// errors are not possible since our WalkFunc never returns one
+	_ = Walk(root, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if parent == nil || parent.Kind != yaml.MappingNode || current.Kind != yaml.ScalarNode {
+			return opts.MissStatus(), nil
+		}
+		newKey, ok := renames[current.Value]
+		if !ok || newKey == current.Value || HasKey(parent, newKey) {
+			return opts.MissStatus(), nil
+		}
+		current.Value = newKey
+		count++
+		return opts.MissStatus(), nil
+	})
+	return count
+}
+
+// EnsureSequence returns `node` unchanged if it is a sequence, otherwise a new
+// sequence node containing `node` as its only element is returned.  This is
+// useful to normalize fields that accept either a single value or a list, for
+// example `args: foo` and `args: [foo, bar]`.  Aliases to sequences are
+// returned unchanged.
+func EnsureSequence(node *yaml.Node) *yaml.Node {
+	if Indirect(node).Kind == yaml.SequenceNode {
+		return node
+	}
+	seq := NewSequenceNode()
+	seq.Line = node.Line
+	seq.Column = node.Column
+	seq.Content = []*yaml.Node{node}
+	return seq
+}
+
+// Singularize is the reverse of EnsureSequence, if `node` is a sequence with
+// exactly one element then the element is returned, otherwise `node` is
+// returned unchanged.
+func Singularize(node *yaml.Node) *yaml.Node {
+	seq := Indirect(node)
+	if seq.Kind != yaml.SequenceNode || len(seq.Content) != 1 {
+		return node
+	}
+	return seq.Content[0]
+}
+
+// NormalizeKeyQuoting will update the style of string mapping keys throughout
+// `root` so they are quoted only when required.  Quotes are removed from keys
+// that would parse back to the same string when written as plain scalars, and
+// double quotes are added to keys that would otherwise be parsed as a
+// different value or type, for example `"123"`, `"true"` or `"a: b"`.  Keys
+// with an explicit tag and keys that are not strings are left unchanged.
+func NormalizeKeyQuoting(root *yaml.Node) {
+	// errors are not possible since our WalkFunc never returns one
+	_ = Walk(root, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if parent == nil || parent.Kind != yaml.MappingNode || current.Kind != yaml.ScalarNode {
+			return opts.MissStatus(), nil
+		}
+		if current.Style&yaml.TaggedStyle != 0 || current.ShortTag() != "!!str" {
+			return opts.MissStatus(), nil
+		}
+		if isPlainSafe(current.Value) {
+			current.Style = 0
+		} else if current.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) == 0 {
+			current.Style = yaml.DoubleQuotedStyle
+		}
+		return opts.MissStatus(), nil
+	})
+}
+
+// isPlainSafe returns true if `value` will be parsed as the same string
+// when written as a plain scalar.
+func isPlainSafe(value string) bool {
+	if value == "" || strings.ContainsAny(value, "\n\r\t") {
+		return false
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		return false
+	}
+	if len(doc.Content) != 1 {
+		return false
+	}
+	node := doc.Content[0]
+	return node.Kind == yaml.ScalarNode && node.Style == 0 &&
+		node.ShortTag() == "!!str" && node.Value == value
+}
+
+// WrapLongFlows will convert flow style mappings and sequences in `root` to
+// block style when the single line flow representation is wider than
+// `maxWidth` characters.  Shorter flow collections are left as they are.  The
+// width only includes the collection itself, not any key or indentation
+// preceding it.  When a collection is converted its nested collections are
+// kept in flow style unless they are also too wide.
+func WrapLongFlows(root *yaml.Node, maxWidth int) {
+	// errors are not possible since our NodeFunc never returns one
+	_ = Walk(root, allNodesWalker(func(node *yaml.Node) error {
+		if node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		if node.Style&yaml.FlowStyle == 0 {
+			return nil
+		}
+		if utf8.RuneCountInString(flowString(node)) <= maxWidth {
+			return nil
+		}
+		node.Style &^= yaml.FlowStyle
+		// nested collections were implicitly flow style, so make that
+		// explicit before they are visited.
+		for _, child := range node.Content {
+			if child.Kind == yaml.MappingNode || child.Kind == yaml.SequenceNode {
+				child.Style |= yaml.FlowStyle
+			}
+		}
+		return nil
+	}))
+}
@@ -0,0 +1,469 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMapKeysCase(t *testing.T) {
+	doc := HereBytes(`
+	Name: Value
+	Nested:
+		MixedCase: 1
+		list:
+			- InList: 2
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	err = walky.MapKeysToLower(&root)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: Value
+		nested:
+			mixedcase: 1
+			list:
+				- inlist: 2
+	`), string(got))
+
+	err = walky.MapKeysToUpper(&root)
+	require.NoError(t, err)
+	got, err = yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		NAME: Value
+		NESTED:
+			MIXEDCASE: 1
+			LIST:
+				- INLIST: 2
+	`), string(got))
+
+	doc = HereBytes(`
+	nested:
+		Key: 1
+		other: 2
+		KEY: 3
+	`)
+	err = yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	err = walky.MapKeysToLower(&root)
+	require.EqualError(t, err, `line 4:5 at "KEY": key collides with "Key" from line 2 as "key"`)
+	// document is unchanged on error
+	got, err = yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, string(doc), string(got))
+}
+
+func TestProject(t *testing.T) {
+	doc := HereBytes(`
+	# database settings
+	database:
+		host: db.example.com
+		port: 5432
+		password: secret
+	servers:
+		- name: web1
+		  port: 80
+		- name: web2
+		  port: 8080
+	logging:
+		level: debug
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	projected, err := walky.Project(&root,
+		[]interface{}{"database", "host"},
+		[]interface{}{"servers", 1, "port"},
+	)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(projected)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		# database settings
+		database:
+			host: db.example.com
+		servers:
+			- port: 8080
+	`), string(got))
+
+	// original is unchanged
+	got, err = yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, string(doc), string(got))
+
+	_, err = walky.Project(&root, []interface{}{1.5})
+	require.EqualError(t, err, "Unable to make PathMatcher from type float64 (1.5)")
+}
+
+func TestNormalizeEmpties(t *testing.T) {
+	doc := Here(`
+	labels: {}
+	annotations:
+	args: []
+	nested:
+		env: {}
+		list:
+			- []
+			- {}
+	`)
+	for _, tt := range []struct {
+		Name     string
+		MapForm  string
+		SeqForm  string
+		Expected string
+	}{{
+		Name:    src(),
+		MapForm: walky.EmptyFlowMap,
+		SeqForm: walky.EmptyFlowSeq,
+		Expected: Here(`
+			labels: {}
+			annotations:
+			args: []
+			nested:
+				env: {}
+				list:
+					- []
+					- {}
+		`),
+	}, {
+		Name:    src(),
+		MapForm: walky.EmptyBlock,
+		SeqForm: walky.EmptyBlock,
+		Expected: Here(`
+			labels:
+			annotations:
+			args:
+			nested:
+				env:
+				list:
+					-
+					-
+		`),
+	}, {
+		Name:    src(),
+		MapForm: walky.EmptyBlock,
+		SeqForm: walky.EmptyFlowSeq,
+		Expected: Here(`
+			labels:
+			annotations:
+			args: []
+			nested:
+				env:
+				list:
+					- []
+					-
+		`),
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var root yaml.Node
+			err := yaml.Unmarshal([]byte(doc), &root)
+			require.NoError(t, err)
+			err = walky.NormalizeEmpties(&root, tt.MapForm, tt.SeqForm)
+			require.NoError(t, err)
+			got, err := yaml.Marshal(&root)
+			require.NoError(t, err)
+			require.Equal(t, tt.Expected, string(got))
+		})
+	}
+
+	// programmatic empty containers use the flow form
+	root := walky.NewMappingNode()
+	err := walky.AssignMapNode(root, walky.NewStringNode("empty"), walky.NewMappingNode())
+	require.NoError(t, err)
+	err = walky.NormalizeEmpties(root, walky.EmptyFlowMap, walky.EmptyFlowSeq)
+	require.NoError(t, err)
+	require.Equal(t, yaml.FlowStyle, walky.GetKey(root, "empty").Style)
+
+	err = walky.NormalizeEmpties(root, "[]", "")
+	require.EqualError(t, err, `invalid empty mapping form "[]"`)
+	err = walky.NormalizeEmpties(root, "", "nope")
+	require.EqualError(t, err, `invalid empty sequence form "nope"`)
+}
+
+func TestCountValue(t *testing.T) {
+	root := parse(t, `
+	primary: db.example.com
+	replicas:
+		- db.example.com
+		- db2.example.com
+		- host: db.example.com
+		  port: 5432
+	ports: [5432, 5433, "5432"]
+	db.example.com: key
+	`)
+	require.Equal(t, 4, walky.CountValue(root, "db.example.com"))
+	require.Equal(t, 1, walky.CountValue(root, "db2.example.com"))
+	require.Equal(t, 2, walky.CountValue(root, 5432))
+	require.Equal(t, 1, walky.CountValue(root, "5432"))
+	require.Equal(t, 0, walky.CountValue(root, "missing"))
+}
+
+func TestReplaceValue(t *testing.T) {
+	root := parse(t, `
+	primary: db.example.com # main
+	replicas:
+		- db.example.com
+		- host: db.example.com
+		  port: 5432
+	ports: [5432, 5433]
+	`)
+	require.Equal(t, 3, walky.ReplaceValue(root, "db.example.com", "db.internal"))
+	require.Equal(t, 2, walky.ReplaceValue(root, 5432, 6432))
+	require.Equal(t, 0, walky.ReplaceValue(root, "missing", "value"))
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		primary: db.internal # main
+		replicas:
+			- db.internal
+			- host: db.internal
+			  port: 6432
+		ports: [6432, 5433]
+	`), string(got))
+
+	// the style is reset when the tag changes
+	root = parse(t, `
+	port: "8080" # quoted
+	name: 'web'
+	count: !!int |-
+		42
+	`)
+	require.Equal(t, 1, walky.ReplaceValue(root, "8080", 9090))
+	require.Equal(t, 1, walky.ReplaceValue(root, "web", "api"))
+	require.Equal(t, 1, walky.ReplaceValue(root, 42, "many"))
+	got, err = yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		port: 9090 # quoted
+		name: 'api'
+		count: many
+	`), string(got))
+
+	// anchors are kept, so aliases still resolve
+	root = parse(t, `
+	host: &h old
+	other: *h
+	`)
+	require.Equal(t, 1, walky.ReplaceValue(root, "old", "new"))
+	got, err = yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, "host: &h new\nother: *h\n", string(got))
+	var decoded yaml.Node
+	require.NoError(t, yaml.Unmarshal(got, &decoded))
+	require.Equal(t, "new", walky.Indirect(walky.GetKey(&decoded, "other")).Value)
+}
+
+func TestTabsInScalars(t *testing.T) {
+	doc := "script: |\n  if true; then\n  \techo yes\n  fi\nquoted: \"a\\tb\"\nplain: no tabs\n"
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(doc), &root)
+	require.NoError(t, err)
+
+	found := walky.TabsInScalars(&root)
+	require.Len(t, found, 2)
+	require.Equal(t, 1, found[0].Line)
+	require.Equal(t, 5, found[1].Line)
+
+	require.Equal(t, 2, walky.ReplaceTabsInScalars(&root, 4))
+	require.Empty(t, walky.TabsInScalars(&root))
+	require.Equal(t, "if true; then\n    echo yes\nfi\n", walky.GetKey(&root, "script").Value)
+	require.Equal(t, "a    b", walky.GetKey(&root, "quoted").Value)
+
+	got, err := yaml.Marshal(&root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		script: |
+			if true; then
+			    echo yes
+			fi
+		quoted: "a    b"
+		plain: no tabs
+	`), string(got))
+}
+
+func TestMigrateKeys(t *testing.T) {
+	root := parse(t, `
+	# the server host
+	hostname: example.com
+	port: 80
+	servers:
+		- hostname: web1 # first
+		  timeout: 30
+		- hostname: web2
+		  host: already-set
+	`)
+	count := walky.MigrateKeys(root, map[string]string{
+		"hostname": "host",
+		"timeout":  "timeoutSeconds",
+		"missing":  "ignored",
+	})
+	require.Equal(t, 3, count)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		# the server host
+		host: example.com
+		port: 80
+		servers:
+			- host: web1 # first
+			  timeoutSeconds: 30
+			- hostname: web2
+			  host: already-set
+	`), string(got))
+}
+
+func TestEnsureSequence(t *testing.T) {
+	root := parse(t, `
+	single: foo
+	list: [foo, bar]
+	mapping: {a: 1}
+	`)
+	m := walky.UnwrapDocument(root)
+	for i := 1; i < len(m.Content); i += 2 {
+		m.Content[i] = walky.EnsureSequence(m.Content[i])
+	}
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		single:
+			- foo
+		list: [foo, bar]
+		mapping:
+			- {a: 1}
+	`), string(got))
+
+	// idempotent
+	seq := m.Content[1]
+	require.Same(t, seq, walky.EnsureSequence(seq))
+}
+
+func TestSingularize(t *testing.T) {
+	root := parse(t, `
+	single: [foo]
+	list: [foo, bar]
+	empty: []
+	scalar: foo
+	mapping: [{a: 1}]
+	`)
+	m := walky.UnwrapDocument(root)
+	for i := 1; i < len(m.Content); i += 2 {
+		m.Content[i] = walky.Singularize(m.Content[i])
+	}
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		single: foo
+		list: [foo, bar]
+		empty: []
+		scalar: foo
+		mapping: {a: 1}
+	`), string(got))
+
+	// round trip
+	node := walky.NewStringNode("foo")
+	require.Same(t, node, walky.Singularize(walky.EnsureSequence(node)))
+}
+
+func TestNormalizeKeyQuoting(t *testing.T) {
+	root := parse(t, `
+	"simple": 1
+	'also simple': 2
+	plain: 3
+	"123": 4
+	"true": 5
+	"a: b": 6
+	"#comment": 7
+	"": 8
+	789: 9
+	!!str 456: 10
+	nested:
+		- "key": 11
+	`)
+	// keys created programmatically have no style
+	m := walky.UnwrapDocument(root)
+	err := walky.AssignMapNode(m, walky.NewStringNode("1.5"), walky.NewIntNode(12))
+	require.NoError(t, err)
+
+	walky.NormalizeKeyQuoting(root)
+
+	styles := map[string]yaml.Style{}
+	err = walky.RangeMap(m, func(key, value *yaml.Node) error {
+		styles[key.Value] = key.Style
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]yaml.Style{
+		"simple":      0,
+		"also simple": 0,
+		"plain":       0,
+		"123":         yaml.DoubleQuotedStyle,
+		"true":        yaml.DoubleQuotedStyle,
+		"a: b":        yaml.DoubleQuotedStyle,
+		"#comment":    yaml.DoubleQuotedStyle,
+		"":            yaml.DoubleQuotedStyle,
+		"789":         0,
+		"456":         yaml.TaggedStyle,
+		"nested":      0,
+		"1.5":         yaml.DoubleQuotedStyle,
+	}, styles)
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		"1.5": 12
+		simple: 1
+		also simple: 2
+		plain: 3
+		"123": 4
+		"true": 5
+		"a: b": 6
+		"#comment": 7
+		"": 8
+		789: 9
+		!!str 456: 10
+		nested:
+			- key: 11
+	`), string(got))
+}
+
+func TestWrapLongFlows(t *testing.T) {
+	root := parse(t, `
+	short: [a, b, c]
+	long: [alpha, bravo, charlie, delta, echo, foxtrot]
+	nested: [[a, b], [alpha, bravo, charlie, delta], {k: v}]
+	block:
+		map: {name: web, image: registry.example.com/web:latest}
+	`)
+	walky.WrapLongFlows(root, 30)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		short: [a, b, c]
+		long:
+			- alpha
+			- bravo
+			- charlie
+			- delta
+			- echo
+			- foxtrot
+		nested:
+			- [a, b]
+			- [alpha, bravo, charlie, delta]
+			- {k: v}
+		block:
+			map:
+				name: web
+				image: registry.example.com/web:latest
+	`), string(got))
+}
@@ -0,0 +1,79 @@
+package walky
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Tree is a simplified, comment-free representation of a yaml.Node for pure
+// data transformations.  For mappings each of the Children is a value with
+// the corresponding key in Key, for sequences the Children are the elements
+// and Key is nil.
+type Tree struct {
+	Kind     yaml.Kind
+	Tag      string
+	Value    string
+	Children []*Tree
+	Key      *Tree
+}
+
+// ToTree converts `node` to a Tree.  Documents are unwrapped, aliases are
+// replaced with the nodes they refer to and `!!merge` keys are expanded, so
+// the Tree only contains mappings, sequences and scalars.  Implicit tags are
+// resolved (see yaml.Node.ShortTag).  Comments, styles, anchors and positions
+// are discarded.  Recursive aliases cannot be expanded and are kept as
+// AliasNode trees with the anchor name as the Value.
+func ToTree(node *yaml.Node) *Tree {
+	resolved, err := resolveNode(UnwrapDocument(node), map[*yaml.Node]bool{})
+	if err != nil {
+		// malformed mappings cannot be resolved, fall back to the
+		// unresolved content.
+		resolved = UnwrapDocument(node)
+	}
+	return toTree(resolved)
+}
+
+func toTree(node *yaml.Node) *Tree {
+	t := &Tree{
+		Kind:  node.Kind,
+		Tag:   node.ShortTag(),
+		Value: node.Value,
+	}
+	if node.Kind == yaml.AliasNode {
+		t.Tag = ""
+		return t
+	}
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			child := toTree(node.Content[i+1])
+			child.Key = toTree(node.Content[i])
+			t.Children = append(t.Children, child)
+		}
+		return t
+	}
+	for _, child := range node.Content {
+		t.Children = append(t.Children, toTree(child))
+	}
+	return t
+}
+
+// FromTree converts `t` back to a *yaml.Node.  Mapping keys are taken from
+// the Key of each of the Children, a child without a Key will have a `!!null`
+// key.
+func FromTree(t *Tree) *yaml.Node {
+	node := &yaml.Node{
+		Kind:  t.Kind,
+		Tag:   t.Tag,
+		Value: t.Value,
+	}
+	for _, child := range t.Children {
+		if t.Kind == yaml.MappingNode {
+			key := NewNullNode()
+			if child.Key != nil {
+				key = FromTree(child.Key)
+			}
+			node.Content = append(node.Content, key)
+		}
+		node.Content = append(node.Content, FromTree(child))
+	}
+	return node
+}
@@ -0,0 +1,66 @@
+package walky_test
+
+import (
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTree(t *testing.T) {
+	root := parse(t, `
+	# the name
+	name: web # inline
+	replicas: 3
+	ports: [80, 443]
+	base: &base
+		image: web:latest
+	spec:
+		<<: *base
+		args:
+			- --verbose
+			- "123"
+	`)
+	tree := walky.ToTree(root)
+	require.Equal(t, yaml.MappingNode, tree.Kind)
+	require.Len(t, tree.Children, 5)
+	require.Equal(t, "replicas", tree.Children[1].Key.Value)
+	require.Equal(t, "!!int", tree.Children[1].Tag)
+	require.Equal(t, "3", tree.Children[1].Value)
+
+	// pure data transformation, double every int
+	var double func(t *walky.Tree)
+	double = func(t *walky.Tree) {
+		if t.Tag == "!!int" {
+			t.Value += "0"
+		}
+		for _, child := range t.Children {
+			double(child)
+		}
+	}
+	double(tree)
+
+	got, err := yaml.Marshal(walky.FromTree(tree))
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		name: web
+		replicas: 30
+		ports:
+			- 800
+			- 4430
+		base:
+			image: web:latest
+		spec:
+			image: web:latest
+			args:
+				- --verbose
+				- "123"
+	`), string(got))
+
+	// round trip preserves data but not comments
+	roundTrip := walky.FromTree(walky.ToTree(root))
+	require.True(t, walky.EqualResolved(root, roundTrip))
+	require.Empty(t, roundTrip.Content[0].HeadComment)
+	require.Empty(t, roundTrip.Content[1].LineComment)
+}
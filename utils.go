@@ -1,12 +1,14 @@
 package walky
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"io"
-	"os"
+	"math"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -33,6 +35,33 @@ func IsNull(node *yaml.Node) bool {
 	return node.Tag == "!!null"
 }
 
+// IsEmpty will return true if the node, after resolving aliases and
+// unwrapping documents, is considered empty.  Empty nodes are:
+//   - nil nodes
+//   - documents without content
+//   - `!!null` scalars (see IsNull)
+//   - `!!str` (or untagged) scalars with an empty value
+//   - mappings and sequences without content
+//
+// Unlike IsNull an empty string, `{}` and `[]` are all empty, while a scalar
+// with an empty value but a non string tag (ie `!!int`) is not.
+func IsEmpty(node *yaml.Node) bool {
+	if node == nil {
+		return true
+	}
+	node = Indirect(node)
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		if IsNull(node) {
+			return true
+		}
+		return node.Value == "" && (node.Tag == "" || node.Tag == "!!str")
+	}
+	return false
+}
+
 func NewDocumentNode() *yaml.Node {
 	return &yaml.Node{
 		Kind: yaml.DocumentNode,
@@ -53,10 +82,85 @@ func NewSequenceNode() *yaml.Node {
 	}
 }
 
+// NewSequenceNodeFromSlice creates a new SequenceNode with each of the
+// `items`, in order, converted with ToNode.  If any item cannot be converted
+// the error for the first failing item is returned, including its index.
+func NewSequenceNodeFromSlice(items ...interface{}) (*yaml.Node, error) {
+	node := NewSequenceNode()
+	for i, item := range items {
+		elem, err := ToNode(item)
+		if err != nil {
+			return nil, fmt.Errorf("index %d: %w", i, err)
+		}
+		node.Content = append(node.Content, elem)
+	}
+	return node, nil
+}
+
+// NewMappingNodeFromMap creates a new MappingNode from `m`, with the keys in
+// sorted order and the values converted with ToNode.  An error is returned if
+// any value cannot be converted.
+func NewMappingNodeFromMap(m map[string]interface{}) (*yaml.Node, error) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	node := NewMappingNode()
+	for _, key := range keys {
+		value, err := ToNode(m[key])
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", key, err)
+		}
+		node.Content = append(node.Content, NewStringNode(key), value)
+	}
+	return node, nil
+}
+
+// StringOption can be provided to NewStringNode to modify the created node.
+type StringOption func(*yaml.Node)
+
+// WithLiteralStyle is a StringOption to emit the string as a literal block
+// scalar (`|`).
+func WithLiteralStyle() StringOption {
+	return func(node *yaml.Node) {
+		node.Style = yaml.LiteralStyle
+	}
+}
+
+// WithFoldedStyle is a StringOption to emit the string as a folded block
+// scalar (`>`).
+func WithFoldedStyle() StringOption {
+	return func(node *yaml.Node) {
+		node.Style = yaml.FoldedStyle
+	}
+}
+
+// WithDoubleQuotedStyle is a StringOption to emit the string in double
+// quotes.
+func WithDoubleQuotedStyle() StringOption {
+	return func(node *yaml.Node) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+}
+
+// WithSingleQuotedStyle is a StringOption to emit the string in single
+// quotes.
+func WithSingleQuotedStyle() StringOption {
+	return func(node *yaml.Node) {
+		node.Style = yaml.SingleQuotedStyle
+	}
+}
+
 // NewStringNode creates a new Node with the value of the provided string.
-func NewStringNode(value string) *yaml.Node {
+// By default the style is chosen by yaml.Node.SetString, the StringOptions
+// can be used to force a specific style.
+func NewStringNode(value string, opts ...StringOption) *yaml.Node {
 	var node yaml.Node
 	node.SetString(value)
+	for _, opt := range opts {
+		opt(&node)
+	}
 	return &node
 }
 
@@ -78,6 +182,16 @@ func NewIntNode(value int64) *yaml.Node {
 	}
 }
 
+// NewUintNode creates a new Node with the value of the provided uint64.  This
+// allows values above math.MaxInt64 which cannot be created with NewIntNode.
+func NewUintNode(value uint64) *yaml.Node {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!int",
+		Value: strconv.FormatUint(value, 10),
+	}
+}
+
 // NewFloatNode creates a new Node with the value of the provided float64.
 func NewFloatNode(value float64) *yaml.Node {
 	return &yaml.Node{
@@ -87,6 +201,61 @@ func NewFloatNode(value float64) *yaml.Node {
 	}
 }
 
+// NewTimestampNode creates a new `!!timestamp` Node with the value of the
+// provided time.Time.  The time is formatted with time.RFC3339Nano (which is
+// RFC3339 with fractional seconds only when present) unless an optional
+// `layout` is provided.  Zero times are formatted as
+// `0001-01-01T00:00:00Z`.
+func NewTimestampNode(t time.Time, layout ...string) *yaml.Node {
+	format := time.RFC3339Nano
+	if len(layout) > 0 {
+		format = layout[0]
+	}
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!timestamp",
+		Value: t.Format(format),
+	}
+}
+
+// NewBinaryNode creates a new `!!binary` Node with the base64 encoding of
+// `data`.  Payloads longer than a single 76 character line are split over
+// multiple lines in a literal block scalar.  See BinaryValue to decode the
+// node.
+func NewBinaryNode(data []byte) *yaml.Node {
+	const lineLength = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	node := &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!!binary",
+		Value: encoded,
+	}
+	if len(encoded) > lineLength {
+		var buf strings.Builder
+		for len(encoded) > 0 {
+			n := lineLength
+			if n > len(encoded) {
+				n = len(encoded)
+			}
+			buf.WriteString(encoded[:n] + "\n")
+			encoded = encoded[n:]
+		}
+		node.Value = buf.String()
+		node.Style = yaml.LiteralStyle
+	}
+	return node
+}
+
+// NewNullNode creates a new `!!null` scalar Node with an empty value, which
+// will be emitted as an empty value (ie `key:`).  IsNull will always return
+// true for the returned node.
+func NewNullNode() *yaml.Node {
+	return &yaml.Node{
+		Kind: yaml.ScalarNode,
+		Tag:  "!!null",
+	}
+}
+
 // KindString will return a human-readable string that represents the
 // yaml.Kind arguments.
 func KindString(k yaml.Kind) string {
@@ -101,10 +270,18 @@ func KindString(k yaml.Kind) string {
 		return "scalar"
 	case yaml.AliasNode:
 		return "alias"
+	case CommentNode:
+		return "comment"
 	}
 	return "unknown"
 }
 
+// ToNode will convert `val` to a *yaml.Node.  If `val` is already a yaml.Node
+// it is returned directly (with any document unwrapped).  If `val` implements
+// yaml.Marshaler then the result of MarshalYAML is converted, so a custom
+// marshaler returning a *yaml.Node will have the tag and style of that node
+// preserved.  Otherwise `val` is marshaled and unmarshaled to produce the
+// node.
 func ToNode(val interface{}) (*yaml.Node, error) {
 	node := yaml.Node{}
 	switch v := val.(type) {
@@ -115,6 +292,12 @@ func ToNode(val interface{}) (*yaml.Node, error) {
 	case string:
 		node.SetString(v)
 		return &node, nil
+	case yaml.Marshaler:
+		marshaled, err := v.MarshalYAML()
+		if err != nil {
+			return nil, err
+		}
+		return ToNode(marshaled)
 	}
 	content, err := yaml.Marshal(val)
 	if err != nil {
@@ -139,6 +322,29 @@ func SortableNodeMap(mapNode *yaml.Node) sort.Interface {
 	return sortableNodeMap(mapNode.Content)
 }
 
+// SortableNodeMapFunc is like SortableNodeMap, but uses `less` to compare the
+// map keys rather than the default ordering.  Key/value pairs are kept
+// together when sorting.  For example to sort keys case-insensitively:
+//
+//	sort.Sort(SortableNodeMapFunc(node, func(a, b *yaml.Node) bool {
+//		return strings.ToLower(a.Value) < strings.ToLower(b.Value)
+//	}))
+func SortableNodeMapFunc(mapNode *yaml.Node, less func(a, b *yaml.Node) bool) sort.Interface {
+	return sortableNodeMapFunc{
+		sortableNodeMap: SortableNodeMap(mapNode).(sortableNodeMap),
+		less:            less,
+	}
+}
+
+type sortableNodeMapFunc struct {
+	sortableNodeMap
+	less func(a, b *yaml.Node) bool
+}
+
+func (sm sortableNodeMapFunc) Less(i, j int) bool {
+	return sm.less(sm.sortableNodeMap[i*2], sm.sortableNodeMap[j*2])
+}
+
 func (sm sortableNodeMap) Len() int {
 	return len(sm) / 2
 }
@@ -149,26 +355,103 @@ func (sm sortableNodeMap) Swap(i, j int) {
 }
 
 // Less compares two keys in a yaml Mapping type.  It will compare the kinds,
-// tags and content length before comparing the value.  It will not recurse
-// into complex types (other than comparign relative size)
+// tags and content length before comparing the value.  Values of `!!int` and
+// `!!float` scalars are compared numerically (so `9` sorts before `10`), other
+// values are compared as strings.  It will not recurse into complex types
+// (other than comparign relative size)
 func (sm sortableNodeMap) Less(i, j int) bool {
 	iIndex, jIndex := i*2, j*2
 	if sm[iIndex].Kind != sm[jIndex].Kind {
 		return sm[iIndex].Kind < sm[jIndex].Kind
 	}
-	if sm[iIndex].Tag != sm[jIndex].Tag {
-		return sm[iIndex].Tag < sm[jIndex].Tag
+	if iTag, jTag := sm[iIndex].ShortTag(), sm[jIndex].ShortTag(); iTag != jTag {
+		return iTag < jTag
 	}
 	if len(sm[iIndex].Content) != len(sm[jIndex].Content) {
 		return len(sm[iIndex].Content) < len(sm[jIndex].Content)
 	}
 
-	// FIXME this comparison needs to parse the numeric values to compare
-	// correctly
+	if less, ok := numericLess(sm[iIndex], sm[jIndex]); ok {
+		return less
+	}
 	return sm[iIndex].Value < sm[jIndex].Value
 }
 
+// numericLess compares the values of the `!!int` or `!!float` scalars `a` and
+// `b` numerically.  It will return false for `ok` if either node is not
+// numeric, or the values are numerically equal (ie `10` and `0xA`) or not
+// comparable (`.nan`), so the caller can fall back to comparing the strings.
+// The values are parsed with strconv rather than Decode, since this is called
+// for every comparison when sorting.
+func numericLess(a, b *yaml.Node) (less bool, ok bool) {
+	if a.Kind != yaml.ScalarNode || b.Kind != yaml.ScalarNode {
+		return false, false
+	}
+	aTag, bTag := a.ShortTag(), b.ShortTag()
+	if aTag == "!!int" && bTag == "!!int" {
+		aInt, aErr := parseIntValue(a.Value)
+		bInt, bErr := parseIntValue(b.Value)
+		if aErr == nil && bErr == nil {
+			return aInt < bInt, aInt != bInt
+		}
+		// fall through to compare as floats if the values overflow int64
+	}
+	if (aTag != "!!int" && aTag != "!!float") || (bTag != "!!int" && bTag != "!!float") {
+		return false, false
+	}
+	aFloat, aOK := parseFloatValue(a.Value)
+	bFloat, bOK := parseFloatValue(b.Value)
+	if !aOK || !bOK {
+		return false, false
+	}
+	if aFloat < bFloat {
+		return true, true
+	}
+	if aFloat > bFloat {
+		return false, true
+	}
+	return false, false
+}
+
+// parseIntValue parses an `!!int` scalar value, allowing the same forms as
+// yaml.v3 (`0x`, `0o` and `0b` prefixes and `_` separators).
+func parseIntValue(value string) (int64, error) {
+	return strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 0, 64)
+}
+
+// parseFloatValue parses a `!!int` or `!!float` scalar value, including the
+// YAML spellings of infinity and NaN.
+func parseFloatValue(value string) (float64, bool) {
+	switch value {
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1), true
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1), true
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN(), true
+	}
+	if i, err := parseIntValue(value); err == nil {
+		return float64(i), true
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64)
+	return f, err == nil
+}
+
+// Equal will return true if the data represented by `a` and `b` is the same.
+// Aliases are resolved and documents unwrapped before comparing, and mapping
+// keys are compared without regard to their order.  Equal is insensitive to
+// the representation of the data, so node Style (flow vs block, quoting etc),
+// comments, anchors and line/column positions are ignored.  Tags are compared
+// after resolving implicit tags, so a node with an empty Tag is equal to a
+// node with the explicit default tag for the same data (for example a
+// programmatically built mapping with no tag is equal to a decoded `{}`).
 func Equal(a *yaml.Node, b *yaml.Node) bool {
+	return equal(a, b, -1)
+}
+
+// equal compares `a` and `b` as described by Equal, recursing into the node
+// content at most `depth` levels.  If `depth` is negative there is no limit.
+func equal(a *yaml.Node, b *yaml.Node, depth int) bool {
 	if a == nil || b == nil {
 		return false
 	}
@@ -177,7 +460,7 @@ func Equal(a *yaml.Node, b *yaml.Node) bool {
 	if a.Kind != b.Kind {
 		return false
 	}
-	if a.Tag != b.Tag {
+	if a.ShortTag() != b.ShortTag() {
 		return false
 	}
 	if a.Value != b.Value {
@@ -186,6 +469,9 @@ func Equal(a *yaml.Node, b *yaml.Node) bool {
 	if len(a.Content) != len(b.Content) {
 		return false
 	}
+	if depth == 0 {
+		return true
+	}
 	if a.Kind == yaml.MappingNode {
 		aContent := make([]*yaml.Node, len(a.Content))
 		bContent := make([]*yaml.Node, len(b.Content))
@@ -194,13 +480,13 @@ func Equal(a *yaml.Node, b *yaml.Node) bool {
 		sort.Sort(sortableNodeMap(aContent))
 		sort.Sort(sortableNodeMap(bContent))
 		for i := 0; i < len(aContent); i++ {
-			if !Equal(aContent[i], bContent[i]) {
+			if !equal(aContent[i], bContent[i], depth-1) {
 				return false
 			}
 		}
 	} else {
 		for i := 0; i < len(a.Content); i++ {
-			if !Equal(a.Content[i], b.Content[i]) {
+			if !equal(a.Content[i], b.Content[i], depth-1) {
 				return false
 			}
 		}
@@ -256,6 +542,7 @@ func AssignMapNode(mapNode, keyNode, valNode *yaml.Node) error {
 }
 
 func AppendNode(listNode, valNode *yaml.Node) error {
+	listNode = UnwrapDocument(listNode)
 	if listNode.Kind != yaml.SequenceNode {
 		return NewYAMLError(
 			fmt.Errorf("AppendNode called on invalid type: %s", listNode.Tag),
@@ -291,6 +578,40 @@ func GetKey(mapNode *yaml.Node, key interface{}) (node *yaml.Node) {
 	return node
 }
 
+// errPathFound is used internally by GetPath to stop walking once the first
+// node is found.
+var errPathFound = errors.New("path found")
+
+// GetPath returns the first node matching `path` (as accepted by WalkPath) in
+// `root`, or nil if there is no match or the path is invalid.  It is the same
+// as GetKey but for deep paths, for example:
+//
+//	image := GetPath(root, "spec", "containers", 0, "image")
+func GetPath(root *yaml.Node, path ...interface{}) (node *yaml.Node) {
+	err := WalkPath(root, func(n *yaml.Node) error {
+		node = n
+		return errPathFound
+	}, path...)
+	if err != nil && !errors.Is(err, errPathFound) {
+		return nil
+	}
+	return node
+}
+
+// GetPathValue returns the value of the scalar node found with GetPath,
+// following aliases.  The bool result is false if there is no match or the
+// node is not a scalar.
+func GetPathValue(root *yaml.Node, path ...interface{}) (string, bool) {
+	node := GetPath(root, path...)
+	if node == nil {
+		return "", false
+	}
+	if node = Indirect(node); node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return node.Value, true
+}
+
 // GetIndex returns the index of the target node found in the parent node.  If
 // the parent is a MappingNode the index corresponds to the key node (the value
 // will be the key node index + 1).   If the parent node is not a SequenceNode
@@ -347,6 +668,65 @@ func Remove(parent *yaml.Node, target *yaml.Node) bool {
 	return true
 }
 
+// RemoveMergingComments will delete the target node from the parent node like
+// Remove, but the head and foot comments from the removed nodes are moved
+// to the adjacent nodes so that they are not lost.  Head comments are
+// prepended to the head comment of the next node (or appended to the foot
+// comment of the previous node if the target was last).  Foot comments are
+// appended to the foot comment of the previous node (or prepended to the head
+// comment of the next node if the target was first).  If the parent has no
+// other content the comments are appended to the parent foot comment.
+// Returns true if and only if the target was found in the parent.
+func RemoveMergingComments(parent *yaml.Node, target *yaml.Node) bool {
+	parent = UnwrapDocument(parent)
+	ix := GetIndex(parent, target)
+	if ix < 0 {
+		return false
+	}
+	step := 1
+	if parent.Kind == yaml.MappingNode {
+		step = 2
+	}
+	head, foot := "", ""
+	for _, removed := range parent.Content[ix : ix+step] {
+		head = joinComments(head, removed.HeadComment)
+		foot = joinComments(foot, removed.FootComment)
+	}
+	parent.Content = append(parent.Content[:ix], parent.Content[ix+step:]...)
+
+	var prev, next *yaml.Node
+	if ix > 0 {
+		// the foot comment for a map entry is usually on the value
+		prev = parent.Content[ix-1]
+	}
+	if ix < len(parent.Content) {
+		next = parent.Content[ix]
+	}
+	switch {
+	case prev == nil && next == nil:
+		parent.FootComment = joinComments(parent.FootComment, head, foot)
+	case next == nil:
+		prev.FootComment = joinComments(prev.FootComment, head, foot)
+	case prev == nil:
+		next.HeadComment = joinComments(head, foot, next.HeadComment)
+	default:
+		prev.FootComment = joinComments(prev.FootComment, foot)
+		next.HeadComment = joinComments(head, next.HeadComment)
+	}
+	return true
+}
+
+// joinComments will join the non-empty comments with newlines.
+func joinComments(comments ...string) string {
+	nonEmpty := []string{}
+	for _, c := range comments {
+		if c != "" {
+			nonEmpty = append(nonEmpty, c)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
 // CopyNode will do a deep copy of the src Node and return a copy
 func CopyNode(src *yaml.Node) *yaml.Node {
 	copied := map[*yaml.Node]*yaml.Node{}
@@ -385,21 +765,6 @@ func ShallowCopyNode(src *yaml.Node) *yaml.Node {
 	return &cp
 }
 
-// ReadFile is a helper function to read a file and return a yaml.Node
-func ReadFile(filepath string) (*yaml.Node, error) {
-	fh, err := os.Open(filepath)
-	if err != nil {
-		return nil, err
-	}
-	defer fh.Close()
-	dec := yaml.NewDecoder(fh)
-	var node yaml.Node
-	if err = dec.Decode(&node); err != nil && !errors.Is(err, io.EOF) {
-		return nil, ErrFilename(err, filepath)
-	}
-	return &node, nil
-}
-
 // Indirect will return the aliased node if this node is an alias,
 // otherwise it will return the original node.
 func Indirect(node *yaml.Node) *yaml.Node {
@@ -565,3 +930,113 @@ func RangeMap(node *yaml.Node, f RangerFunc, opts ...RangeOption) error {
 	}
 	return nil
 }
+
+// IndexedRangerFunc is the callback used to iterate over a map via
+// RangeMapIndexed.  It is the same as RangerFunc but is also passed the
+// zero-based index of the key/value pair.
+type IndexedRangerFunc func(index int, key, value *yaml.Node) error
+
+// RangeMapIndexed will iterate over `node` the same as RangeMap, but will
+// also provide the zero-based index of each key/value pair to the
+// IndexedRangerFunc.  The index reflects the order the pairs are visited, so
+// when `!!merge` keys are present the merged pairs are counted where they are
+// visited (which depends on WithMergesLast) and suppressed duplicate keys are
+// not counted at all.
+func RangeMapIndexed(node *yaml.Node, f IndexedRangerFunc, opts ...RangeOption) error {
+	index := 0
+	return RangeMap(node, func(key, value *yaml.Node) error {
+		err := f(index, key, value)
+		index++
+		return err
+	}, opts...)
+}
+
+// RangeSeq will iterate over the sequence `node`, calling `f` with the index
+// and value of each element.  An error will be returned if the node is not a
+// sequence node (or an alias referencing a sequence node), a `!!null` node is
+// treated as an empty sequence.  If `f` returns an error it will be returned
+// immediately, to stop iteration without RangeSeq returning an error, you can
+// return ErrStopRange.
+func RangeSeq(node *yaml.Node, f func(index int, value *yaml.Node) error) error {
+	node = Indirect(node)
+	if IsNull(node) {
+		return nil
+	}
+	if node.Kind != yaml.SequenceNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.SequenceNode), KindString(node.Kind)),
+			node,
+		)
+	}
+	for i, elem := range node.Content {
+		if err := f(i, elem); err != nil {
+			if errors.Is(err, ErrStopRange) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// LeafFunc is the callback used by RangeLeaves, it is called with the full
+// path to each leaf scalar node.
+type LeafFunc func(path []interface{}, value *yaml.Node) error
+
+// errStopLeaves is used internally by RangeLeaves to stop iterating,
+// since RangeMap will consume ErrStopRange at each level.
+var errStopLeaves = errors.New("stop leaves")
+
+// RangeLeaves will call the LeafFunc for every leaf scalar node in `root` in
+// document order, along with the path to the leaf.  The path elements are
+// strings for mapping keys (or the *yaml.Node for non scalar keys) and ints
+// for sequence indexes, so they can be used with WalkPath.  Aliases are
+// followed and `!!merge` keys are expanded (using RangeMap), recursive aliases
+// are not followed more than once.  Empty mappings and sequences have no
+// leaves so are skipped.  If the LeafFunc returns ErrStopRange the iteration
+// stops and nil is returned, other errors are returned immediately.
+func RangeLeaves(root *yaml.Node, fn LeafFunc) error {
+	err := rangeLeaves(UnwrapDocument(root), []interface{}{}, map[*yaml.Node]bool{}, func(path []interface{}, value *yaml.Node) error {
+		err := fn(path, value)
+		if errors.Is(err, ErrStopRange) {
+			return errStopLeaves
+		}
+		return err
+	})
+	if errors.Is(err, errStopLeaves) {
+		return nil
+	}
+	return err
+}
+
+func rangeLeaves(node *yaml.Node, path []interface{}, visiting map[*yaml.Node]bool, fn LeafFunc) error {
+	node = Indirect(node)
+	if visiting[node] {
+		return nil
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		leafPath := make([]interface{}, len(path))
+		copy(leafPath, path)
+		return fn(leafPath, node)
+	case yaml.MappingNode:
+		visiting[node] = true
+		defer delete(visiting, node)
+		return RangeMap(node, func(key, value *yaml.Node) error {
+			var elem interface{} = key
+			if key = Indirect(key); key.Kind == yaml.ScalarNode {
+				elem = key.Value
+			}
+			return rangeLeaves(value, append(path, elem), visiting, fn)
+		})
+	case yaml.SequenceNode:
+		visiting[node] = true
+		defer delete(visiting, node)
+		for i, elem := range node.Content {
+			if err := rangeLeaves(elem, append(path, i), visiting, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
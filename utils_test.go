@@ -0,0 +1,712 @@
+package walky_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRangeMapIndexed(t *testing.T) {
+	doc := HereBytes(`
+	defs:
+		- &common {a: 1, b: 2}
+	plain:
+		x: 1
+		y: 2
+		z: 3
+	merged:
+		<<: *common
+		c: 3
+		d: 4
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	type pair struct {
+		Index int
+		Key   string
+	}
+	collect := func(node *yaml.Node, opts ...walky.RangeOption) []pair {
+		got := []pair{}
+		err := walky.RangeMapIndexed(node, func(index int, key, value *yaml.Node) error {
+			got = append(got, pair{index, key.Value})
+			return nil
+		}, opts...)
+		require.NoError(t, err)
+		return got
+	}
+
+	require.Equal(t,
+		[]pair{{0, "x"}, {1, "y"}, {2, "z"}},
+		collect(walky.GetKey(&root, "plain")),
+	)
+	require.Equal(t,
+		[]pair{{0, "a"}, {1, "b"}, {2, "c"}, {3, "d"}},
+		collect(walky.GetKey(&root, "merged")),
+	)
+	require.Equal(t,
+		[]pair{{0, "c"}, {1, "d"}, {2, "a"}, {3, "b"}},
+		collect(walky.GetKey(&root, "merged"), walky.WithMergesLast()),
+	)
+}
+
+func TestIsEmpty(t *testing.T) {
+	doc := HereBytes(`
+	null: null
+	tilde: ~
+	implicit:
+	emptyString: ""
+	emptyMap: {}
+	emptySeq: []
+	aliasEmpty: &empty {}
+	toEmpty: *empty
+	zero: 0
+	false: false
+	string: value
+	space: " "
+	map: {a: 1}
+	seq: [1]
+	aliasFull: &full [1]
+	toFull: *full
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	for _, key := range []string{"null", "tilde", "implicit", "emptyString", "emptyMap", "emptySeq", "aliasEmpty", "toEmpty"} {
+		require.True(t, walky.IsEmpty(walky.GetKey(&root, key)), key)
+	}
+	for _, key := range []string{"zero", "false", "string", "space", "map", "seq", "aliasFull", "toFull"} {
+		require.False(t, walky.IsEmpty(walky.GetKey(&root, key)), key)
+	}
+	require.True(t, walky.IsEmpty(walky.NewDocumentNode()))
+	require.True(t, walky.IsEmpty(walky.NewStringNode("")))
+	require.False(t, walky.IsEmpty(&root))
+}
+
+func TestEqualIgnoresStyle(t *testing.T) {
+	doc := HereBytes(`
+	flowSeq: [1, 2, 3]
+	blockSeq:
+		- 1
+		- 2
+		- 3
+	flowMap: {a: 1, b: [x, y]}
+	blockMap:
+		b:
+			- x
+			- y
+		a: 1
+	emptyFlowMap: {}
+	emptyFlowSeq: []
+	quoted: "value"
+	literal: |-
+		value
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	get := func(key string) *yaml.Node {
+		node := walky.GetKey(&root, key)
+		require.NotNil(t, node, key)
+		return node
+	}
+
+	require.True(t, walky.Equal(get("flowSeq"), get("blockSeq")))
+	require.True(t, walky.Equal(get("flowMap"), get("blockMap")))
+	require.True(t, walky.Equal(get("quoted"), get("literal")))
+
+	require.True(t, walky.Equal(get("emptyFlowMap"), walky.NewMappingNode()))
+	require.True(t, walky.Equal(get("emptyFlowMap"), &yaml.Node{Kind: yaml.MappingNode}))
+	require.True(t, walky.Equal(get("emptyFlowSeq"), walky.NewSequenceNode()))
+	require.True(t, walky.Equal(get("emptyFlowSeq"), &yaml.Node{Kind: yaml.SequenceNode}))
+	require.False(t, walky.Equal(get("emptyFlowMap"), get("emptyFlowSeq")))
+
+	built := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, v := range []string{"1", "2", "3"} {
+		built.Content = append(built.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+	}
+	require.True(t, walky.Equal(get("flowSeq"), built))
+	require.True(t, walky.Equal(get("blockSeq"), built))
+	require.False(t, walky.Equal(get("quoted"), &yaml.Node{Kind: yaml.ScalarNode, Value: "1"}))
+}
+
+func TestRemoveMergingComments(t *testing.T) {
+	for _, tt := range []struct {
+		Name     string
+		Input    string
+		Parent   selectors
+		Remove   interface{}
+		Expected string
+	}{{
+		Name: src(),
+		Input: Here(`
+			first: 1
+			# describes the next keys
+			removed: 2
+			last: 3
+		`),
+		Remove: "removed",
+		Expected: Here(`
+			first: 1
+			# describes the next keys
+			last: 3
+		`),
+	}, {
+		Name: src(),
+		Input: Here(`
+			first: 1
+			# about last
+			last: 3
+		`),
+		Remove: "last",
+		Expected: Here(`
+			first: 1
+			# about last
+		`),
+	}, {
+		Name: src(),
+		Input: Here(`
+			list:
+				# about one
+				- one
+				- two
+		`),
+		Parent: selectors{"list"},
+		Remove: "one",
+		Expected: Here(`
+			list:
+				# about one
+				- two
+		`),
+	}} {
+		t.Run(tt.Name, func(t *testing.T) {
+			var root yaml.Node
+			err := yaml.Unmarshal([]byte(tt.Input), &root)
+			require.NoError(t, err)
+
+			parent := &root
+			if len(tt.Parent) > 0 {
+				err = walky.WalkPath(&root, func(node *yaml.Node) error {
+					parent = node
+					return nil
+				}, tt.Parent...)
+				require.NoError(t, err)
+			}
+			target, err := walky.ToNode(tt.Remove)
+			require.NoError(t, err)
+			require.True(t, walky.RemoveMergingComments(parent, target))
+
+			got, err := yaml.Marshal(&root)
+			require.NoError(t, err)
+			require.Equal(t, tt.Expected, string(got))
+		})
+	}
+}
+
+type secretValue string
+
+func (s secretValue) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{
+		Kind:  yaml.ScalarNode,
+		Tag:   "!secret",
+		Value: string(s),
+		Style: yaml.DoubleQuotedStyle,
+	}, nil
+}
+
+type upperValue string
+
+func (u upperValue) MarshalYAML() (interface{}, error) {
+	return map[string]string{"upper": string(u)}, nil
+}
+
+func TestToNodeMarshaler(t *testing.T) {
+	node, err := walky.ToNode(secretValue("hunter2"))
+	require.NoError(t, err)
+	require.Equal(t, "!secret", node.Tag)
+	require.Equal(t, yaml.DoubleQuotedStyle, node.Style)
+	require.Equal(t, "hunter2", node.Value)
+
+	node, err = walky.ToNode(upperValue("ABC"))
+	require.NoError(t, err)
+	require.Equal(t, yaml.MappingNode, node.Kind)
+	require.Equal(t, "ABC", walky.GetKey(node, "upper").Value)
+
+	root := walky.NewMappingNode()
+	node, err = walky.ToNode(secretValue("hunter2"))
+	require.NoError(t, err)
+	err = walky.AssignMapNode(root, walky.NewStringNode("password"), node)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, "password: !secret \"hunter2\"\n", string(got))
+}
+
+func TestRangeLeaves(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		image: app:1.0
+	name: web
+	spec:
+		<<: *defaults
+		ports: [80, 443]
+		empty: {}
+		env:
+			- name: DEBUG
+			  value: "true"
+	recursive: &r [1, *r]
+	`)
+
+	type leaf struct {
+		Path  string
+		Value string
+	}
+	got := []leaf{}
+	err := walky.RangeLeaves(root, func(path []interface{}, value *yaml.Node) error {
+		got = append(got, leaf{walky.PathString(path...), value.Value})
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []leaf{
+		{"defaults.image", "app:1.0"},
+		{"name", "web"},
+		{"spec.image", "app:1.0"},
+		{"spec.ports[0]", "80"},
+		{"spec.ports[1]", "443"},
+		{"spec.env[0].name", "DEBUG"},
+		{"spec.env[0].value", "true"},
+		{"recursive[0]", "1"},
+	}, got)
+
+	// paths can be used with WalkPath
+	err = walky.RangeLeaves(root, func(path []interface{}, value *yaml.Node) error {
+		if len(path) > 0 && path[0] == "spec" && path[1] == "image" {
+			// merged keys are not reachable via WalkPath
+			return nil
+		}
+		found := false
+		err := walky.WalkPath(root, func(node *yaml.Node) error {
+			found = true
+			require.Equal(t, value, node)
+			return nil
+		}, path...)
+		require.NoError(t, err)
+		require.True(t, found, walky.PathString(path...))
+		return nil
+	})
+	require.NoError(t, err)
+
+	count := 0
+	err = walky.RangeLeaves(root, func(path []interface{}, value *yaml.Node) error {
+		count++
+		if count == 3 {
+			return walky.ErrStopRange
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+}
+
+func TestNewNullNode(t *testing.T) {
+	node := walky.NewNullNode()
+	require.True(t, walky.IsNull(node))
+	require.True(t, walky.IsEmpty(node))
+
+	root := parse(t, `
+	explicit: null
+	tilde: ~
+	empty:
+	`)
+	err := walky.RangeMap(root, func(key, value *yaml.Node) error {
+		require.True(t, walky.IsNull(value), key.Value)
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, walky.Equal(node, walky.GetKey(root, "empty")))
+
+	m := walky.NewMappingNode()
+	err = walky.AssignMapNode(m, walky.NewStringNode("optional"), node)
+	require.NoError(t, err)
+	got, err := yaml.Marshal(m)
+	require.NoError(t, err)
+	require.Equal(t, "optional:\n", string(got))
+}
+
+func TestNewTimestampNode(t *testing.T) {
+	ts := time.Date(2001, 12, 14, 21, 59, 43, 0, time.UTC)
+	node := walky.NewTimestampNode(ts)
+	require.Equal(t, "!!timestamp", node.Tag)
+	require.Equal(t, "2001-12-14T21:59:43Z", node.Value)
+
+	root := parse(t, `created: 2001-12-14T21:59:43Z`)
+	require.True(t, walky.Equal(walky.GetKey(root, "created"), node))
+
+	var decoded time.Time
+	err := node.Decode(&decoded)
+	require.NoError(t, err)
+	require.True(t, ts.Equal(decoded))
+
+	node = walky.NewTimestampNode(ts.Add(500 * time.Millisecond))
+	require.Equal(t, "2001-12-14T21:59:43.5Z", node.Value)
+
+	node = walky.NewTimestampNode(ts, "2006-01-02")
+	require.Equal(t, "2001-12-14", node.Value)
+	require.Equal(t, "!!timestamp", node.Tag)
+
+	node = walky.NewTimestampNode(time.Time{})
+	require.Equal(t, "0001-01-01T00:00:00Z", node.Value)
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, "0001-01-01T00:00:00Z\n", string(got))
+	err = node.Decode(&decoded)
+	require.NoError(t, err)
+	require.True(t, decoded.IsZero())
+}
+
+func TestNewUintNode(t *testing.T) {
+	for _, value := range []uint64{0, 42, math.MaxInt64 + 1, math.MaxUint64} {
+		node := walky.NewUintNode(value)
+		require.Equal(t, "!!int", node.Tag)
+
+		out, err := yaml.Marshal(node)
+		require.NoError(t, err)
+		var got uint64
+		err = yaml.Unmarshal(out, &got)
+		require.NoError(t, err)
+		require.Equal(t, value, got)
+	}
+	require.Equal(t, "18446744073709551615", walky.NewUintNode(math.MaxUint64).Value)
+	require.True(t, walky.Equal(walky.NewUintNode(42), walky.NewIntNode(42)))
+}
+
+func TestNewStringNodeStyle(t *testing.T) {
+	script := "#!/bin/sh\necho hello\n"
+	for _, tt := range []struct {
+		name     string
+		value    string
+		opts     []walky.StringOption
+		expected string
+	}{{
+		name:     "default",
+		value:    "plain",
+		expected: "plain\n",
+	}, {
+		name:     "literal",
+		value:    script,
+		opts:     []walky.StringOption{walky.WithLiteralStyle()},
+		expected: "|\n    #!/bin/sh\n    echo hello\n",
+	}, {
+		name:     "literal single line",
+		value:    "echo hello",
+		opts:     []walky.StringOption{walky.WithLiteralStyle()},
+		expected: "|-\n    echo hello\n",
+	}, {
+		name:     "folded",
+		value:    "some long text",
+		opts:     []walky.StringOption{walky.WithFoldedStyle()},
+		expected: ">-\n    some long text\n",
+	}, {
+		name:     "double quoted",
+		value:    "plain",
+		opts:     []walky.StringOption{walky.WithDoubleQuotedStyle()},
+		expected: "\"plain\"\n",
+	}, {
+		name:     "single quoted",
+		value:    "plain",
+		opts:     []walky.StringOption{walky.WithSingleQuotedStyle()},
+		expected: "'plain'\n",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			node := walky.NewStringNode(tt.value, tt.opts...)
+			require.Equal(t, "!!str", node.Tag)
+			got, err := yaml.Marshal(node)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(got))
+
+			var decoded string
+			err = yaml.Unmarshal(got, &decoded)
+			require.NoError(t, err)
+			require.Equal(t, tt.value, decoded)
+		})
+	}
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalYAML() (interface{}, error) {
+	return nil, errors.New("cannot marshal")
+}
+
+func TestNewMappingNodeFromMap(t *testing.T) {
+	node, err := walky.NewMappingNodeFromMap(map[string]interface{}{
+		"name":     "web",
+		"replicas": 3,
+		"enabled":  true,
+		"ports":    []int{80, 443},
+		"labels":   map[string]string{"tier": "frontend"},
+		"existing": walky.NewFloatNode(1.5),
+	})
+	require.NoError(t, err)
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		enabled: true
+		existing: 1.5
+		labels:
+			tier: frontend
+		name: web
+		ports:
+			- 80
+			- 443
+		replicas: 3
+	`), string(got))
+
+	node, err = walky.NewMappingNodeFromMap(nil)
+	require.NoError(t, err)
+	require.Equal(t, yaml.MappingNode, node.Kind)
+	require.Empty(t, node.Content)
+
+	_, err = walky.NewMappingNodeFromMap(map[string]interface{}{
+		"ok":  1,
+		"bad": failingMarshaler{},
+	})
+	require.EqualError(t, err, `key "bad": cannot marshal`)
+}
+
+func TestNewSequenceNodeFromSlice(t *testing.T) {
+	node, err := walky.NewSequenceNodeFromSlice("web", 3, true, 1.5, nil, []string{"a"}, walky.NewStringNode("node"))
+	require.NoError(t, err)
+	got, err := yaml.Marshal(node)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		- web
+		- 3
+		- true
+		- 1.5
+		- null
+		- - a
+		- node
+	`), string(got))
+
+	node, err = walky.NewSequenceNodeFromSlice()
+	require.NoError(t, err)
+	require.Equal(t, yaml.SequenceNode, node.Kind)
+	require.Empty(t, node.Content)
+
+	_, err = walky.NewSequenceNodeFromSlice("ok", failingMarshaler{}, failingMarshaler{})
+	require.EqualError(t, err, "index 1: cannot marshal")
+}
+
+func TestRangeSeq(t *testing.T) {
+	root := parse(t, `
+	list: &list [a, b, c, d]
+	alias: *list
+	empty: ~
+	map: {a: 1}
+	`)
+	collect := func(node *yaml.Node) []string {
+		t.Helper()
+		got := []string{}
+		err := walky.RangeSeq(node, func(index int, value *yaml.Node) error {
+			got = append(got, fmt.Sprintf("%d=%s", index, value.Value))
+			return nil
+		})
+		require.NoError(t, err)
+		return got
+	}
+	require.Equal(t, []string{"0=a", "1=b", "2=c", "3=d"}, collect(walky.GetKey(root, "list")))
+	require.Equal(t, []string{"0=a", "1=b", "2=c", "3=d"}, collect(walky.GetKey(root, "alias")))
+	require.Empty(t, collect(walky.GetKey(root, "empty")))
+
+	got := []string{}
+	err := walky.RangeSeq(walky.GetKey(root, "list"), func(index int, value *yaml.Node) error {
+		if index == 2 {
+			return walky.ErrStopRange
+		}
+		got = append(got, value.Value)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, got)
+
+	err = walky.RangeSeq(walky.GetKey(root, "list"), func(index int, value *yaml.Node) error {
+		return fmt.Errorf("boom")
+	})
+	require.EqualError(t, err, "boom")
+
+	err = walky.RangeSeq(walky.GetKey(root, "map"), func(index int, value *yaml.Node) error {
+		return nil
+	})
+	require.EqualError(t, err, `line 4:6: expected node kind "sequence", got "mapping"`)
+	require.IsType(t, walky.YAMLError{}, err)
+}
+
+func TestSortableNodeMapNumeric(t *testing.T) {
+	root := parse(t, `
+	10: ten
+	9: nine
+	0x3: three
+	100: hundred
+	1.5: one and a half
+	-2.5: negative
+	.inf: infinity
+	-.Inf: negative infinity
+	1_000: thousand
+	b: bee
+	a: ay
+	`)
+	sort.Sort(walky.SortableNodeMap(root))
+	keys := []string{}
+	err := walky.RangeMap(root, func(key, value *yaml.Node) error {
+		keys = append(keys, key.Value)
+		return nil
+	})
+	require.NoError(t, err)
+	// floats sort before ints and strings since the tags are compared first
+	require.Equal(t, []string{"-.Inf", "-2.5", "1.5", ".inf", "0x3", "9", "10", "100", "1_000", "a", "b"}, keys)
+
+	// Equal sorts the keys, so the order of numeric keys does not matter
+	require.True(t, walky.Equal(
+		parse(t, `{10: a, 9: b, 100: c, 1.5: d}`),
+		parse(t, `{9: b, 1.5: d, 100: c, 10: a}`),
+	))
+	require.False(t, walky.Equal(
+		parse(t, `{10: a, 9: b, 100: c}`),
+		parse(t, `{9: a, 10: b, 100: c}`),
+	))
+	require.False(t, walky.Equal(
+		parse(t, `{10: a, 0xA: b}`),
+		parse(t, `{0xA: a, 10: b}`),
+	))
+	require.True(t, walky.Equal(
+		parse(t, `{10: a, 0xA: b}`),
+		parse(t, `{0xA: b, 10: a}`),
+	))
+}
+
+func TestSortableNodeMapFunc(t *testing.T) {
+	root := parse(t, `
+	spec:
+		replicas: 3
+	metadata:
+		name: web
+	kind: Deployment
+	Zeta: z
+	apiVersion: apps/v1
+	`)
+	order := map[string]int{"apiVersion": 1, "kind": 2, "metadata": 3, "spec": 4}
+	sort.Sort(walky.SortableNodeMapFunc(root, func(a, b *yaml.Node) bool {
+		ai, bi := order[a.Value], order[b.Value]
+		if ai == 0 || bi == 0 {
+			// unknown keys sort after known keys
+			return ai > bi
+		}
+		return ai < bi
+	}))
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		apiVersion: apps/v1
+		kind: Deployment
+		metadata:
+			name: web
+		spec:
+			replicas: 3
+		Zeta: z
+	`), string(got))
+
+	sort.Sort(walky.SortableNodeMapFunc(root, func(a, b *yaml.Node) bool {
+		return strings.ToLower(a.Value) < strings.ToLower(b.Value)
+	}))
+	got, err = yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		apiVersion: apps/v1
+		kind: Deployment
+		metadata:
+			name: web
+		spec:
+			replicas: 3
+		Zeta: z
+	`), string(got))
+
+	sort.Sort(walky.SortableNodeMapFunc(root, func(a, b *yaml.Node) bool {
+		return a.Value < b.Value
+	}))
+	require.Equal(t, "Zeta", root.Content[0].Content[0].Value)
+	require.Equal(t, "z", root.Content[0].Content[1].Value)
+
+	// non-mappings are a no-op
+	scalar := walky.NewStringNode("value")
+	sort.Sort(walky.SortableNodeMapFunc(scalar, nil))
+	require.Equal(t, "value", scalar.Value)
+}
+
+func TestGetPath(t *testing.T) {
+	root := parse(t, `
+	image: &image web:1.0
+	spec:
+		containers:
+			- name: web
+			  image: *image
+			- name: sidecar
+			  image: proxy:2.0
+		replicas: 3
+	`)
+	node := walky.GetPath(root, "spec", "containers", 1, "name")
+	require.NotNil(t, node)
+	require.Equal(t, "sidecar", node.Value)
+	require.Equal(t, yaml.SequenceNode, walky.GetPath(root, "spec", "containers").Kind)
+	require.Equal(t, yaml.MappingNode, walky.GetPath(root).Kind)
+
+	// the first match is returned
+	node = walky.GetPath(root, "spec", regexp.MustCompile(`^(containers|replicas)$`))
+	require.Equal(t, yaml.SequenceNode, node.Kind)
+
+	require.Nil(t, walky.GetPath(root, "spec", "missing"))
+	require.Nil(t, walky.GetPath(root, "spec", "containers", 5))
+	require.Nil(t, walky.GetPath(root, 1.5))
+
+	value, ok := walky.GetPathValue(root, "spec", "replicas")
+	require.True(t, ok)
+	require.Equal(t, "3", value)
+	value, ok = walky.GetPathValue(root, "spec", "containers", 0, "image")
+	require.True(t, ok)
+	require.Equal(t, "web:1.0", value)
+	_, ok = walky.GetPathValue(root, "spec", "containers")
+	require.False(t, ok)
+	_, ok = walky.GetPathValue(root, "spec", "missing")
+	require.False(t, ok)
+}
+
+func TestAppendNodeDocument(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "list.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("- a\n- b\n"), 0o644))
+	root, err := walky.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, yaml.DocumentNode, root.Kind)
+
+	err = walky.AppendNode(root, walky.NewStringNode("c"))
+	require.NoError(t, err)
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, "- a\n- b\n- c\n", string(got))
+
+	err = walky.AppendNode(parse(t, `a: 1`), walky.NewStringNode("c"))
+	require.EqualError(t, err, "line 1:1: AppendNode called on invalid type: !!map")
+}
@@ -0,0 +1,259 @@
+package walky
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckScalarLengths will return a YAMLErrors list containing an error for
+// every scalar node (keys and values) in `root` with a Value longer than `max`
+// bytes.  If no scalars exceed `max` then nil is returned.
+func CheckScalarLengths(root *yaml.Node, max int) error {
+	errs := YAMLErrors{}
+	check := func(node *yaml.Node) error {
+		if node.Kind != yaml.ScalarNode || len(node.Value) <= max {
+			return nil
+		}
+		// the Context is intentionally left empty, the value is
+		// by definition too large to be useful in an error message.
+		errs = append(errs, YAMLError{
+			Line:   node.Line,
+			Column: node.Column,
+			Err:    fmt.Errorf("scalar length %d exceeds maximum %d", len(node.Value), max),
+		})
+		return nil
+	}
+	err := Walk(root, allNodesWalker(check))
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckEnum returns a YAMLError if `node` is not a scalar with a Value
+// matching one of the `allowed` values.  The error message lists the valid
+// options.
+func CheckEnum(node *yaml.Node, allowed ...string) error {
+	node = Indirect(node)
+	if node.Kind != yaml.ScalarNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.ScalarNode), KindString(node.Kind)),
+			node,
+		)
+	}
+	for _, value := range allowed {
+		if node.Value == value {
+			return nil
+		}
+	}
+	return NewYAMLError(
+		fmt.Errorf("invalid value %q, must be one of: %s", node.Value, strings.Join(allowed, ", ")),
+		node,
+	)
+}
+
+// Located is a node along with the path to the node from the root, where the
+// path elements are suitable for WalkPath.
+type Located struct {
+	Path []interface{}
+	Node *yaml.Node
+}
+
+// UnknownFields returns the map keys found in `node` that do not correspond to
+// a field in the struct type of `sample`, similar to yaml.Decoder.KnownFields.
+// Field names are determined the same way as yaml.v3, using the `yaml` struct
+// tag or the lowercased field name, and `inline` structs are supported.  Keys
+// are checked recursively for nested structs, and for slices and maps of
+// structs.  Keys included via `!!merge` are checked as well.  `sample` should
+// be a struct or a pointer to a struct.
+func UnknownFields(node *yaml.Node, sample interface{}) []Located {
+	unknown := []Located{}
+	unknownFields(UnwrapDocument(node), reflect.TypeOf(sample), nil, &unknown)
+	return unknown
+}
+
+func unknownFields(node *yaml.Node, typ reflect.Type, path []interface{}, unknown *[]Located) {
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil {
+		return
+	}
+	node = Indirect(node)
+	child := func(elem interface{}) []interface{} {
+		return append(path[:len(path):len(path)], elem)
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields, inlineMap := structFields(typ)
+		_ = RangeMap(node, func(key, value *yaml.Node) error {
+			field, ok := fields[key.Value]
+			if !ok {
+				if !inlineMap {
+					*unknown = append(*unknown, Located{Path: child(key.Value), Node: key})
+				}
+				return nil
+			}
+			unknownFields(value, field, child(key.Value), unknown)
+			return nil
+		})
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, elem := range node.Content {
+			unknownFields(elem, typ.Elem(), child(i), unknown)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		_ = RangeMap(node, func(key, value *yaml.Node) error {
+			unknownFields(value, typ.Elem(), child(key.Value), unknown)
+			return nil
+		})
+	}
+}
+
+// structFields returns the yaml field names of the struct type mapped to the
+// field types, and whether the struct has an inline map which will accept any
+// key.
+func structFields(typ reflect.Type) (map[string]reflect.Type, bool) {
+	fields := map[string]reflect.Type{}
+	inlineMap := false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "" && !strings.Contains(string(field.Tag), ":") {
+			tag = string(field.Tag)
+		}
+		if tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		name := parts[0]
+		inline := false
+		for _, flag := range parts[1:] {
+			if flag == "inline" {
+				inline = true
+			}
+		}
+		if inline {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			switch fieldType.Kind() {
+			case reflect.Map:
+				inlineMap = true
+			case reflect.Struct:
+				inlineFields, inlineInlineMap := structFields(fieldType)
+				for name, typ := range inlineFields {
+					fields[name] = typ
+				}
+				inlineMap = inlineMap || inlineInlineMap
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields, inlineMap
+}
+
+// RequireKeys returns a YAMLErrors list with an error for each of the `keys`
+// that is not present in `mapNode`, including keys provided with `!!merge`.
+// The errors point at the mapping node since the key is absent.  If all keys
+// are present nil is returned.
+func RequireKeys(mapNode *yaml.Node, keys ...string) error {
+	mapNode = Indirect(mapNode)
+	if mapNode.Kind != yaml.MappingNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.MappingNode), KindString(mapNode.Kind)),
+			mapNode,
+		)
+	}
+	present := map[string]bool{}
+	err := RangeMap(mapNode, func(key, value *yaml.Node) error {
+		if key.Kind == yaml.ScalarNode {
+			present[key.Value] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	errs := YAMLErrors{}
+	for _, key := range keys {
+		if !present[key] {
+			errs = append(errs, YAMLError{
+				Line:   mapNode.Line,
+				Column: mapNode.Column,
+				Err:    fmt.Errorf("missing required key %q", key),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CheckUniqueBy returns a YAMLErrors list with an error for each element of
+// `seqNode` that has the same scalar value for `key` as an earlier element.
+// The errors point at the duplicate element and mention the line of the
+// first occurrence.  Elements that are not mappings, or do not have a scalar
+// value for `key`, are ignored.
+func CheckUniqueBy(seqNode *yaml.Node, key string) error {
+	seqNode = Indirect(seqNode)
+	if seqNode.Kind != yaml.SequenceNode {
+		return NewYAMLError(
+			fmt.Errorf("expected node kind %q, got %q", KindString(yaml.SequenceNode), KindString(seqNode.Kind)),
+			seqNode,
+		)
+	}
+	first := map[string]*yaml.Node{}
+	errs := YAMLErrors{}
+	for _, elem := range seqNode.Content {
+		elem = Indirect(elem)
+		if elem.Kind != yaml.MappingNode {
+			continue
+		}
+		value := GetKey(elem, key)
+		if value == nil {
+			continue
+		}
+		value = Indirect(value)
+		if value.Kind != yaml.ScalarNode {
+			continue
+		}
+		if prev, ok := first[value.Value]; ok {
+			errs = append(errs, YAMLError{
+				Line:   elem.Line,
+				Column: elem.Column,
+				Err:    fmt.Errorf("duplicate %s %q, first defined on line %d", key, value.Value, prev.Line),
+			})
+			continue
+		}
+		first[value.Value] = elem
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
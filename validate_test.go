@@ -0,0 +1,190 @@
+package walky_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coryb/walky"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestCheckScalarLengths(t *testing.T) {
+	doc := HereBytes(`
+	short: value
+	nested:
+		list:
+			- ok
+			- abcdefghijklmnopqrstuvwxyz
+		abcdefghijklmnopqrstuvwxyz: ok
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	err = walky.CheckScalarLengths(&root, 100)
+	require.NoError(t, err)
+
+	err = walky.CheckScalarLengths(&root, 10)
+	require.Error(t, err)
+	var errs walky.YAMLErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, 5, errs[0].Line)
+	require.Equal(t, 6, errs[1].Line)
+	require.Equal(t, "line 5:11: scalar length 26 exceeds maximum 10\nline 6:5: scalar length 26 exceeds maximum 10", err.Error())
+}
+
+func TestCheckEnum(t *testing.T) {
+	root := parse(t, `
+	logLevel: info
+	other:
+		logLevel: trace
+	nested: {logLevel: [debug]}
+	`)
+	levels := []string{"debug", "info", "warn", "error"}
+	check := func(node *yaml.Node) error {
+		return walky.CheckEnum(node, levels...)
+	}
+
+	err := walky.WalkPath(root, check, "logLevel")
+	require.NoError(t, err)
+
+	err = walky.WalkPath(root, check, "other", "logLevel")
+	require.EqualError(t, err, `line 3:15 at "trace": invalid value "trace", must be one of: debug, info, warn, error`)
+	var yerr walky.YAMLError
+	require.True(t, errors.As(err, &yerr))
+	require.Equal(t, 3, yerr.Line)
+
+	err = walky.WalkPath(root, check, "nested", "logLevel")
+	require.EqualError(t, err, `line 4:20: expected node kind "scalar", got "sequence"`)
+}
+
+func TestUnknownFields(t *testing.T) {
+	type Server struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port,omitempty"`
+	}
+	type Common struct {
+		Name string `yaml:"name"`
+	}
+	type Config struct {
+		Common   `yaml:",inline"`
+		Servers  []Server          `yaml:"servers"`
+		Primary  *Server           `yaml:"primary"`
+		Named    map[string]Server `yaml:"named"`
+		Labels   map[string]string `yaml:"labels"`
+		Internal string            `yaml:"-"`
+		Timeout  int
+	}
+	root := parse(t, `
+	defaults: &defaults
+		host: localhost
+		prot: 80
+	name: web
+	nmae: typo
+	timeout: 30
+	internal: hidden
+	labels:
+		anything: ok
+	primary:
+		<<: *defaults
+		port: 80
+	servers:
+		- host: web1
+		  port: 80
+		- hots: web2
+	named:
+		extra:
+			host: web3
+			debug: true
+	`)
+	unknown := walky.UnknownFields(root, &Config{})
+	paths := [][]interface{}{}
+	lines := []int{}
+	for _, u := range unknown {
+		paths = append(paths, u.Path)
+		lines = append(lines, u.Node.Line)
+	}
+	require.Equal(t, [][]interface{}{
+		{"defaults"},
+		{"nmae"},
+		{"internal"},
+		{"primary", "prot"},
+		{"servers", 1, "hots"},
+		{"named", "extra", "debug"},
+	}, paths)
+	require.Equal(t, []int{1, 5, 7, 3, 16, 20}, lines)
+
+	require.Empty(t, walky.UnknownFields(parse(t, `{host: a, port: 1}`), Server{}))
+}
+
+func TestRequireKeys(t *testing.T) {
+	root := parse(t, `
+	defaults: &defaults
+		timeout: 30
+	server:
+		<<: *defaults
+		host: example.com
+		port: 80
+	`)
+	var server *yaml.Node
+	err := walky.WalkPath(root, func(n *yaml.Node) error {
+		server = n
+		return nil
+	}, "server")
+	require.NoError(t, err)
+
+	err = walky.RequireKeys(server, "host", "port", "timeout")
+	require.NoError(t, err)
+
+	err = walky.RequireKeys(server, "host", "tls")
+	require.EqualError(t, err, `line 4:5: missing required key "tls"`)
+
+	err = walky.RequireKeys(server, "name", "host", "tls")
+	var errs walky.YAMLErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, `line 4:5: missing required key "name"`, errs[0].Error())
+	require.Equal(t, `line 4:5: missing required key "tls"`, errs[1].Error())
+
+	err = walky.RequireKeys(walky.GetKey(server, "host"), "name")
+	require.EqualError(t, err, `line 5:11 at "example.com": expected node kind "mapping", got "scalar"`)
+}
+
+func TestCheckUniqueBy(t *testing.T) {
+	root := parse(t, `
+	unique:
+		- name: web
+		  image: web:1.0
+		- name: sidecar
+		- image: no-name
+	duplicates:
+		- name: web
+		  image: web:1.0
+		- name: sidecar
+		- name: web
+		  image: web:2.0
+		- name: web
+	`)
+	get := func(key string) *yaml.Node {
+		return walky.GetKey(root, key)
+	}
+
+	err := walky.CheckUniqueBy(get("unique"), "name")
+	require.NoError(t, err)
+
+	err = walky.CheckUniqueBy(get("duplicates"), "name")
+	var errs walky.YAMLErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 2)
+	require.Equal(t, 10, errs[0].Line)
+	require.Equal(t, `line 10:7: duplicate name "web", first defined on line 7`, errs[0].Error())
+	require.Equal(t, `line 12:7: duplicate name "web", first defined on line 7`, errs[1].Error())
+
+	err = walky.CheckUniqueBy(get("unique"), "image")
+	require.NoError(t, err)
+
+	err = walky.CheckUniqueBy(root, "name")
+	require.EqualError(t, err, `line 1:1: expected node kind "sequence", got "mapping"`)
+}
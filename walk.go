@@ -1,7 +1,12 @@
 package walky
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"path"
+	"regexp"
 
 	"gopkg.in/yaml.v3"
 )
@@ -39,7 +44,12 @@ type WalkOptions struct {
 	missStatus  WalkStatus
 	matchStatus *WalkStatus
 	maxDepth    int
+	budget      int
+	visited     int
+	comments    bool
+	ctx         context.Context
 	trace       func(current, parent *yaml.Node, pos, depth int, status WalkStatus, err error)
+	tracePath   func(path []interface{}, current, parent *yaml.Node, pos, depth int, status WalkStatus, err error)
 }
 
 func (opts *WalkOptions) MissStatus() WalkStatus {
@@ -74,26 +84,157 @@ func WithMaxDepth(max int) WalkOpt {
 	}
 }
 
+// ErrNodeBudgetExceeded is returned from Walk when more nodes would be visited
+// than allowed by WithNodeBudget.
+var ErrNodeBudgetExceeded = errors.New("node budget exceeded")
+
+// WithNodeBudget limits Walk to calling the WalkFunc at most `n` times.  If
+// the walk would visit more than `n` nodes then Walk will stop and return
+// ErrNodeBudgetExceeded.  This bounds the work done for untrusted documents
+// regardless of their shape.  Comment pseudo-nodes (see WithComments) do not
+// count against the budget.
+func WithNodeBudget(n int) WalkOpt {
+	return func(opt *WalkOptions) {
+		opt.budget = n
+	}
+}
+
+// spend will account for visiting a node, returning ErrNodeBudgetExceeded
+// if the budget from WithNodeBudget has been used up, or the context error if
+// the context from WalkContext is done.
+func (opts *WalkOptions) spend() error {
+	if err := opts.ctx.Err(); err != nil {
+		return err
+	}
+	if opts.budget >= 0 && opts.visited >= opts.budget {
+		return ErrNodeBudgetExceeded
+	}
+	opts.visited++
+	return nil
+}
+
 func WithTrace(f func(current, parent *yaml.Node, pos, depth int, ws WalkStatus, err error)) WalkOpt {
 	return func(opt *WalkOptions) {
 		opt.trace = f
 	}
 }
 
+// WithTracePath is like WithTrace, but the callback is also provided with the
+// path (as accepted by WalkPath) from the walk root to the traced node.  For
+// map keys the path includes the key, so it is the path to the map value.
+// Scalar keys are added to the path as strings, other keys as the *yaml.Node.
+// The path can be rendered with PathString.
+func WithTracePath(f func(path []interface{}, current, parent *yaml.Node, pos, depth int, ws WalkStatus, err error)) WalkOpt {
+	return func(opt *WalkOptions) {
+		opt.tracePath = f
+	}
+}
+
+// CommentNode is the yaml.Kind used for the comment pseudo-nodes passed to
+// the WalkFunc when Walk is called with WithComments.
+const CommentNode yaml.Kind = 1 << 8
+
+// WithComments will cause Walk to also call the WalkFunc with comment
+// pseudo-nodes, in document order relative to the nodes that own them.  The
+// pseudo-node will have a Kind of CommentNode, the comment text as the Value
+// and the text will also be set on exactly one of HeadComment, LineComment or
+// FootComment to indicate the comment placement.  The parent argument to the
+// WalkFunc will be the node that owns the comment and the position will be
+// -1, so a WalkFunc must check for `current.Kind == CommentNode` before
+// indexing the parent by position (such as `parent.Content[pos+1]` for map
+// values).  Head comments are visited before the owning node, line comments
+// directly after and foot comments after the owning node's children have been
+// visited (unless the walk is breadth-first, in which case the foot comments
+// are visited directly after the line comments).  The WalkStatus returned
+// for a comment is ignored unless it is WalkExit.
+func WithComments() WalkOpt {
+	return func(opt *WalkOptions) {
+		opt.comments = true
+	}
+}
+
+type commentPlacement int
+
+const (
+	headComment commentPlacement = iota
+	lineComment
+	footComment
+)
+
+// walkComments will call `f` with a comment pseudo-node for each non-empty
+// comment on `owner` at the requested placements.  If the walk should exit
+// then true is returned.
+func walkComments(f WalkFunc, owner *yaml.Node, opts *WalkOptions, placements ...commentPlacement) (bool, error) {
+	if !opts.comments {
+		return false, nil
+	}
+	for _, placement := range placements {
+		comment := &yaml.Node{
+			Kind:   CommentNode,
+			Line:   owner.Line,
+			Column: owner.Column,
+		}
+		switch placement {
+		case headComment:
+			comment.HeadComment = owner.HeadComment
+			comment.Value = owner.HeadComment
+		case lineComment:
+			comment.LineComment = owner.LineComment
+			comment.Value = owner.LineComment
+		case footComment:
+			comment.FootComment = owner.FootComment
+			comment.Value = owner.FootComment
+		}
+		if comment.Value == "" {
+			continue
+		}
+		ws, err := f(comment, owner, -1, opts)
+		if err != nil || ws == WalkExit {
+			return true, err
+		}
+	}
+	return false, nil
+}
+
 func Walk(node *yaml.Node, f WalkFunc, walkOpts ...WalkOpt) error {
+	return WalkContext(context.Background(), node, f, walkOpts...)
+}
+
+// WalkContext is the same as Walk, but the walk will stop and return
+// `ctx.Err()` if `ctx` is cancelled or times out.  The context is checked
+// before each node is visited, including nodes queued for breadth-first
+// walks.
+func WalkContext(ctx context.Context, node *yaml.Node, f WalkFunc, walkOpts ...WalkOpt) error {
 	opts := &WalkOptions{
 		missStatus: WalkDepthFirst,
 		maxDepth:   -1,
+		budget:     -1,
+		ctx:        ctx,
 	}
 
 	for _, o := range walkOpts {
 		o(opts)
 	}
+	doc := node
 	node = UnwrapDocument(node)
+	if doc != node {
+		if exit, err := walkComments(f, doc, opts, headComment); exit {
+			return err
+		}
+	}
+	if exit, err := walkComments(f, node, opts, headComment); exit {
+		return err
+	}
+	if err := opts.spend(); err != nil {
+		return err
+	}
 	ws, err := f(node, nil, -1, opts)
 	if opts.trace != nil {
 		opts.trace(node, nil, -1, 0, ws, err)
 	}
+	if opts.tracePath != nil {
+		opts.tracePath(nil, node, nil, -1, 0, ws, err)
+	}
 	if err != nil {
 		return err
 	}
@@ -103,8 +244,11 @@ func Walk(node *yaml.Node, f WalkFunc, walkOpts ...WalkOpt) error {
 	case WalkPrune:
 		return nil
 	}
+	if exit, err := walkComments(f, node, opts, lineComment); exit {
+		return err
+	}
 
-	ws, later, err := walk(node, nil, f, 0, opts)
+	ws, later, err := walk(node, nil, nil, f, 0, opts)
 	if err != nil {
 		return err
 	}
@@ -118,7 +262,7 @@ func Walk(node *yaml.Node, f WalkFunc, walkOpts ...WalkOpt) error {
 	for i := 0; i < len(later); i++ {
 		ws, more, err := later[i]()
 		if err != nil {
-			return nil
+			return err
 		}
 		switch ws {
 		case WalkExit:
@@ -128,40 +272,70 @@ func Walk(node *yaml.Node, f WalkFunc, walkOpts ...WalkOpt) error {
 		}
 		later = append(later, more...)
 	}
+	if _, err := walkComments(f, node, opts, footComment); err != nil {
+		return err
+	}
+	if doc != node {
+		if _, err := walkComments(f, doc, opts, footComment); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 type nextFunc func() (WalkStatus, []nextFunc, error)
 
-func walk(node, parent *yaml.Node, f WalkFunc, depth int, opts *WalkOptions) (WalkStatus, []nextFunc, error) {
+// walk will call `f` for the content of `node`.  The `path` is the path to
+// `node` from the walk root, and is only maintained when tracing with
+// WithTracePath.
+func walk(node, parent *yaml.Node, path []interface{}, f WalkFunc, depth int, opts *WalkOptions) (WalkStatus, []nextFunc, error) {
 	if opts.maxDepth >= 0 && depth > opts.maxDepth {
 		return WalkPrune, nil, nil
 	}
 	walkLater := []nextFunc{}
 	for i := 0; i < len(node.Content); i++ {
-		ws, err := f(node.Content[i], node, i, opts)
+		current := node.Content[i]
+		if exit, err := walkComments(f, current, opts, headComment); exit {
+			return WalkExit, nil, err
+		}
+		if err := opts.spend(); err != nil {
+			return WalkExit, nil, err
+		}
+		ws, err := f(current, node, i, opts)
 		if opts.trace != nil {
-			opts.trace(node.Content[i], node, i, depth, ws, err)
+			opts.trace(current, node, i, depth, ws, err)
+		}
+		var subPath []interface{}
+		if opts.tracePath != nil {
+			subPath = childPath(path, node, i)
+			opts.tracePath(subPath, current, node, i, depth, ws, err)
 		}
 		if err != nil {
 			return ws, nil, err
 		}
-		subNode := node.Content[i]
+		if ws == WalkExit {
+			return WalkExit, nil, nil
+		}
+		if exit, err := walkComments(f, current, opts, lineComment); exit {
+			return WalkExit, nil, err
+		}
+		subNode := current
 		subParent := parent
 		// we only call walkFunc on keys of maps, if the walkFunc wants to
 		// reference value it will use parent.Content[position+1]
 		if node.Kind == yaml.MappingNode {
 			// the parent of a map value is the key, not the whole map
 			subNode = node.Content[i+1]
-			subParent = node.Content[i]
+			subParent = current
 			i++
+			if exit, err := walkComments(f, subNode, opts, headComment, lineComment); exit {
+				return WalkExit, nil, err
+			}
 		}
 		switch ws {
-		case WalkExit:
-			return WalkExit, nil, nil
 		case WalkDepthFirst:
 			// depth-first
-			ws, later, err := walk(subNode, subParent, f, depth+1, opts)
+			ws, later, err := walk(subNode, subParent, subPath, f, depth+1, opts)
 			if err != nil {
 				return ws, nil, err
 			}
@@ -175,15 +349,38 @@ func walk(node, parent *yaml.Node, f WalkFunc, depth int, opts *WalkOptions) (Wa
 		case WalkBreadthFirst:
 			// breadth-first
 			walkLater = append(walkLater, func() (WalkStatus, []nextFunc, error) {
-				return walk(subNode, subParent, f, depth+1, opts)
+				return walk(subNode, subParent, subPath, f, depth+1, opts)
 			})
 		case WalkPrune:
 			return WalkDepthFirst, walkLater, nil
 		}
+		if subNode != current {
+			if exit, err := walkComments(f, subNode, opts, footComment); exit {
+				return WalkExit, nil, err
+			}
+		}
+		if exit, err := walkComments(f, current, opts, footComment); exit {
+			return WalkExit, nil, err
+		}
 	}
 	return WalkDepthFirst, walkLater, nil
 }
 
+// childPath returns a copy of `path` extended with the path element for the
+// content of `node` at position `pos`.
+func childPath(path []interface{}, node *yaml.Node, pos int) []interface{} {
+	sub := make([]interface{}, len(path), len(path)+1)
+	copy(sub, path)
+	if node.Kind != yaml.MappingNode {
+		return append(sub, pos)
+	}
+	key := node.Content[pos]
+	if key.Kind == yaml.ScalarNode {
+		return append(sub, key.Value)
+	}
+	return append(sub, key)
+}
+
 func ScalarValuesWalker(f NodeFunc) WalkFunc {
 	return func(current, parent *yaml.Node, pos int, opts *WalkOptions) (ws WalkStatus, err error) {
 		if current.Kind != yaml.ScalarNode || parent.Kind == yaml.MappingNode {
@@ -194,13 +391,47 @@ func ScalarValuesWalker(f NodeFunc) WalkFunc {
 	}
 }
 
+// KeysWalker is used with Walk to apply `f` to the key nodes of every mapping
+// in the document, the dual of ScalarValuesWalker.  Merge keys (`<<`) are
+// included, but aliases are not followed so keys from merged mappings are
+// only visited where they are defined.
+func KeysWalker(f NodeFunc) WalkFunc {
+	return func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if current.Kind == CommentNode || parent == nil || parent.Kind != yaml.MappingNode {
+			return opts.missStatus, nil
+		}
+		err := f(current)
+		return opts.missStatus, err
+	}
+}
+
+// allNodesWalker is used with Walk to apply `f` to every node in document
+// order.  Walk only calls the WalkFunc with the keys of maps, so this will
+// also call `f` with each map value directly after the key.
+func allNodesWalker(f NodeFunc) WalkFunc {
+	return func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if current.Kind == CommentNode {
+			return opts.missStatus, nil
+		}
+		if err := f(current); err != nil {
+			return opts.missStatus, err
+		}
+		if parent != nil && parent.Kind == yaml.MappingNode {
+			if err := f(parent.Content[pos+1]); err != nil {
+				return opts.missStatus, err
+			}
+		}
+		return opts.missStatus, nil
+	}
+}
+
 // StringWalker is used with Walk to apply `f` to map values that match the
 // provided key string.  If the match is against a map key then the `NodeFunc`
 // will be called with the map value.  If the match is not a map key, then
 // the `NodeFunc` will be called on the matched node.
 func StringWalker(key string, f NodeFunc) WalkFunc {
 	return func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
-		if current.Value != key {
+		if current.Kind == CommentNode || current.Value != key {
 			return opts.missStatus, nil
 		}
 		if parent != nil && parent.Kind == yaml.MappingNode {
@@ -214,7 +445,7 @@ func StringWalker(key string, f NodeFunc) WalkFunc {
 
 func IndexWalker(ix int, f NodeFunc) WalkFunc {
 	return func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
-		if parent == nil || parent.Kind != yaml.SequenceNode {
+		if parent == nil || parent.Kind != yaml.SequenceNode || current.Kind == CommentNode {
 			return opts.missStatus, nil
 		}
 		if ix > pos {
@@ -253,7 +484,7 @@ type nodePathMatcher yaml.Node
 
 func (pm *nodePathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
 	return Walk(node, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
-		if !Equal((*yaml.Node)(pm), current) {
+		if current.Kind == CommentNode || !Equal((*yaml.Node)(pm), current) {
 			return opts.missStatus, nil
 		}
 		if parent != nil && parent.Kind == yaml.MappingNode {
@@ -265,6 +496,99 @@ func (pm *nodePathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
 	}, WithMaxDepth(0))
 }
 
+// RegexMatcher returns a PathMatcher that matches every map key with a scalar
+// value matching `re`.  The NodeFunc is called with the map value for each
+// matching key.  Nodes that are not mappings are skipped.
+func RegexMatcher(re *regexp.Regexp) PathMatcher {
+	return regexPathMatcher{re: re}
+}
+
+type regexPathMatcher struct {
+	re *regexp.Regexp
+}
+
+func (pm regexPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	return matchKeys(node, pm.re.MatchString, fn)
+}
+
+// GlobMatcher returns a PathMatcher that matches every map key with a scalar
+// value matching the shell-style glob `pattern`, as supported by path.Match
+// (ie `service-*`).  The NodeFunc is called with the map value for each
+// matching key.  Nodes that are not mappings are skipped.  The Match method
+// will return path.ErrBadPattern if the pattern is malformed.
+func GlobMatcher(pattern string) PathMatcher {
+	return globPathMatcher(pattern)
+}
+
+type globPathMatcher string
+
+func (pm globPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	if _, err := path.Match(string(pm), ""); err != nil {
+		return err
+	}
+	return matchKeys(node, func(key string) bool {
+		// the pattern was validated above, so there is no error
+		matched, _ := path.Match(string(pm), key)
+		return matched
+	}, fn)
+}
+
+// RecursiveMatcher returns a PathMatcher that applies the `inner` matcher to
+// the node and every node below it, similar to `..` in JSONPath.  For example
+// this will call `fn` for the value of every `image` key in the document:
+//
+//	WalkPathMatchers(root, fn, RecursiveMatcher(StringMatcher("image")))
+//
+// Aliases are followed, and each node is only visited once, so `inner` is
+// only applied once to anchored nodes even if there are many aliases to them.
+func RecursiveMatcher(inner PathMatcher) PathMatcher {
+	return recursivePathMatcher{inner: inner}
+}
+
+type recursivePathMatcher struct {
+	inner PathMatcher
+}
+
+func (pm recursivePathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	visited := map[*yaml.Node]bool{}
+	var descend func(n *yaml.Node) error
+	descend = func(n *yaml.Node) error {
+		n = Indirect(n)
+		if visited[n] {
+			return nil
+		}
+		visited[n] = true
+		if err := pm.inner.Match(n, fn); err != nil {
+			return err
+		}
+		for _, child := range n.Content {
+			if err := descend(child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return descend(node)
+}
+
+// matchKeys calls `fn` with the value of each key in the mapping `node` with
+// a scalar value for which `match` returns true.
+func matchKeys(node *yaml.Node, match func(string) bool, fn NodeFunc) error {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	return Walk(node, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if parent == nil || parent.Kind != yaml.MappingNode || current.Kind != yaml.ScalarNode || !match(current.Value) {
+			return opts.missStatus, nil
+		}
+		err := fn(parent.Content[pos+1])
+		return opts.MatchStatus(), err
+	}, WithMaxDepth(0))
+}
+
+// IndexMatcher returns a PathMatcher that matches the element at index `i` of
+// a sequence.  Negative indices count back from the end of the sequence, so
+// -1 is the last element.  Indices out of range do not match.
 func IndexMatcher(i int) PathMatcher {
 	return indexPathMatcher(i)
 }
@@ -275,7 +599,133 @@ func (pm indexPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
 	if node.Kind != yaml.SequenceNode {
 		return nil
 	}
-	return Walk(node, IndexWalker(int(pm), fn), WithMaxDepth(0))
+	ix := int(pm)
+	if ix < 0 {
+		ix += len(node.Content)
+		if ix < 0 {
+			return nil
+		}
+	}
+	return Walk(node, IndexWalker(ix, fn), WithMaxDepth(0))
+}
+
+// RangeToEnd can be used as the `end` argument to RangeMatcher to match all
+// elements from `start` to the end of the sequence.
+const RangeToEnd = math.MaxInt
+
+// RangeMatcher returns a PathMatcher that matches the elements of a sequence
+// with an index in the range [start, end).  Negative values count back from
+// the end of the sequence, the same as IndexMatcher, and the range is clamped
+// to the length of the sequence.  Use RangeToEnd as `end` to match through the
+// end of the sequence.
+func RangeMatcher(start, end int) PathMatcher {
+	return rangePathMatcher{start: start, end: end}
+}
+
+type rangePathMatcher struct {
+	start, end int
+}
+
+func (pm rangePathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	if node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	clamp := func(ix int) int {
+		if ix < 0 {
+			ix += len(node.Content)
+		}
+		if ix < 0 {
+			return 0
+		}
+		if ix > len(node.Content) {
+			return len(node.Content)
+		}
+		return ix
+	}
+	start, end := clamp(pm.start), clamp(pm.end)
+	if start >= end {
+		return nil
+	}
+	// copy the elements so fn can safely modify the sequence
+	elems := append([]*yaml.Node{}, node.Content[start:end]...)
+	for _, elem := range elems {
+		if err := fn(elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PredicateMatcher returns a PathMatcher that matches the children of the
+// current node for which `pred` returns true.  For mappings `pred` and the
+// NodeFunc are called with each map value, for sequences they are called
+// with each element.  Scalar nodes have no children, so never match.  For
+// example to match every item that is a Deployment:
+//
+//	WalkPathMatchers(root, fn, StringMatcher("items"), PredicateMatcher(func(n *yaml.Node) bool {
+//		kind := GetKey(n, "kind")
+//		return kind != nil && kind.Value == "Deployment"
+//	}))
+func PredicateMatcher(pred func(node *yaml.Node) bool) PathMatcher {
+	return predicatePathMatcher(pred)
+}
+
+type predicatePathMatcher func(node *yaml.Node) bool
+
+func (pm predicatePathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	var children []*yaml.Node
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			children = append(children, node.Content[i])
+		}
+	case yaml.SequenceNode:
+		// copy the elements so fn can safely modify the sequence
+		children = append(children, node.Content...)
+	}
+	for _, child := range children {
+		if !pm(child) {
+			continue
+		}
+		if err := fn(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TypeMatcher returns a PathMatcher that matches the children of the current
+// node with the given `kind`, in the same way as PredicateMatcher.  Aliases
+// are resolved with Indirect before the kind is compared, and the NodeFunc is
+// called with the resolved node, so an alias never matches yaml.AliasNode.
+func TypeMatcher(kind yaml.Kind) PathMatcher {
+	return indirectPathMatcher(func(n *yaml.Node) bool {
+		return n.Kind == kind
+	})
+}
+
+// TagMatcher returns a PathMatcher that matches the children of the current
+// node with the given `tag`, in the same way as PredicateMatcher.  The tag can
+// be in short (`!!str`) or long (`tag:yaml.org,2002:str`) form, and is
+// compared to the resolved tag, so plain scalars such as `42` match `!!int`.
+// Aliases are resolved with Indirect before the tag is compared, and the
+// NodeFunc is called with the resolved node.
+func TagMatcher(tag string) PathMatcher {
+	return indirectPathMatcher(func(n *yaml.Node) bool {
+		return n.ShortTag() == tag || n.LongTag() == tag
+	})
+}
+
+// indirectPathMatcher is a predicatePathMatcher that resolves aliases before
+// calling `pred` and the NodeFunc.
+type indirectPathMatcher func(node *yaml.Node) bool
+
+func (pm indirectPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	return predicatePathMatcher(func(n *yaml.Node) bool {
+		return pm(Indirect(n))
+	}).Match(node, func(n *yaml.Node) error {
+		return fn(Indirect(n))
+	})
 }
 
 func AnyMatcher(walkOpts ...WalkOpt) PathMatcher {
@@ -290,6 +740,11 @@ type anyPathMatcher struct {
 
 func (pm *anyPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
 	return Walk(node, func(current, parent *yaml.Node, pos int, opts *WalkOptions) (WalkStatus, error) {
+		if current.Kind == CommentNode {
+			// comments are not path elements, even if WithComments
+			// was requested
+			return opts.missStatus, nil
+		}
 		if parent != nil && parent.Kind == yaml.MappingNode {
 			// match on all map keys, so send map value as next node
 			err := fn(parent.Content[pos+1])
@@ -311,19 +766,150 @@ func WalkPathMatchers(root *yaml.Node, fn NodeFunc, matchers ...PathMatcher) err
 	return matchFn(UnwrapDocument(root))
 }
 
+// PathOption can be provided as an element of the `path` argument to WalkPath
+// to modify how the path is walked.  PathOptions do not match any nodes
+// themselves and can be placed anywhere in the path.
+type PathOption func(*pathOptions)
+
+type pathOptions struct {
+	createMissing bool
+}
+
+// WithCreateMissing is a PathOption for WalkPath that will create any missing
+// nodes along the path, so the NodeFunc is always called unless an error is
+// returned.  Missing string and *yaml.Node path elements are added as keys
+// (see AssignMapNode) to mappings, and a missing int path element is appended
+// to a sequence, which is first padded with `!!null` elements to reach the
+// index.  Intermediate nodes are created as mappings or sequences depending on
+// the type of the following path element, and the final node is created as a
+// `!!null` scalar.  Null scalars found along the path are converted to
+// mappings or sequences as needed, and an error is returned for any other node
+// that is not the mapping or sequence required by the path element, or for a
+// negative index beyond the start of a sequence.  Pattern path elements (such
+// as *regexp.Regexp) do not name a key, so they are never created, and nothing
+// is created for the path element before a pattern since the pattern could
+// not match anything in it.  For example:
+//
+//	err := WalkPath(root, func(node *yaml.Node) error {
+//		AssignNode(node, NewStringNode("web"))
+//		return nil
+//	}, "metadata", "labels", "app", WithCreateMissing())
+func WithCreateMissing() PathOption {
+	return func(opts *pathOptions) {
+		opts.createMissing = true
+	}
+}
+
 func WalkPath(root *yaml.Node, fn NodeFunc, path ...interface{}) error {
-	matchers := []PathMatcher{}
+	opts := pathOptions{}
+	segments := []interface{}{}
 	for _, p := range path {
+		if opt, ok := p.(PathOption); ok {
+			opt(&opts)
+			continue
+		}
+		segments = append(segments, p)
+	}
+	matchers := []PathMatcher{}
+	for i, p := range segments {
+		var matcher PathMatcher
 		switch pp := p.(type) {
 		case string:
-			matchers = append(matchers, StringMatcher(pp))
+			matcher = StringMatcher(pp)
 		case int:
-			matchers = append(matchers, IndexMatcher(pp))
+			matcher = IndexMatcher(pp)
 		case *yaml.Node:
-			matchers = append(matchers, NodeMatcher(pp))
+			matcher = NodeMatcher(pp)
+		case *regexp.Regexp:
+			matcher = RegexMatcher(pp)
 		default:
 			return fmt.Errorf("Unable to make PathMatcher from type %T (%v)", p, p)
 		}
+		if opts.createMissing {
+			var next interface{}
+			if i+1 < len(segments) {
+				next = segments[i+1]
+			}
+			matcher = &createPathMatcher{matcher: matcher, segment: p, next: next}
+		}
+		matchers = append(matchers, matcher)
 	}
 	return WalkPathMatchers(root, fn, matchers...)
 }
+
+// createPathMatcher wraps a PathMatcher to create the node for `segment` if
+// the wrapped matcher does not match anything.  The `next` segment is used to
+// determine the kind of node to create.
+type createPathMatcher struct {
+	matcher PathMatcher
+	segment interface{}
+	next    interface{}
+}
+
+func (pm *createPathMatcher) Match(node *yaml.Node, fn NodeFunc) error {
+	matched := false
+	err := pm.matcher.Match(node, func(n *yaml.Node) error {
+		matched = true
+		return fn(n)
+	})
+	if err != nil || matched {
+		return err
+	}
+
+	var created *yaml.Node
+	switch pm.next.(type) {
+	case *regexp.Regexp:
+		// a pattern can never match anything in a new node, so creating
+		// one would only leave an empty node behind
+		return nil
+	case string, *yaml.Node:
+		created = NewMappingNode()
+	case int:
+		created = NewSequenceNode()
+	default:
+		created = NewNullNode()
+	}
+
+	switch seg := pm.segment.(type) {
+	case int:
+		if IsNull(node) {
+			AssignNode(node, NewSequenceNode())
+		}
+		if node.Kind != yaml.SequenceNode {
+			return NewYAMLError(
+				fmt.Errorf("expected node kind %q, got %q", KindString(yaml.SequenceNode), KindString(node.Kind)),
+				node,
+			)
+		}
+		if seg < 0 {
+			return Errorf(node, "sequence index %d out of range", seg)
+		}
+		for len(node.Content) < seg {
+			node.Content = append(node.Content, NewNullNode())
+		}
+		node.Content = append(node.Content, created)
+	case string, *yaml.Node:
+		if IsNull(node) {
+			AssignNode(node, NewMappingNode())
+		}
+		if node.Kind != yaml.MappingNode {
+			return NewYAMLError(
+				fmt.Errorf("expected node kind %q, got %q", KindString(yaml.MappingNode), KindString(node.Kind)),
+				node,
+			)
+		}
+		var key *yaml.Node
+		if k, ok := seg.(string); ok {
+			key = NewStringNode(k)
+		} else {
+			key = CopyNode(seg.(*yaml.Node))
+		}
+		if err := AssignMapNode(node, key, created); err != nil {
+			return err
+		}
+	default:
+		// patterns do not identify a key to create
+		return nil
+	}
+	return fn(created)
+}
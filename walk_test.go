@@ -1,9 +1,11 @@
 package walky_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -240,6 +242,20 @@ func TestWalkPath(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, matchFound)
 
+	// comments are skipped by AnyMatcher
+	commented := parse(t, `
+	# head
+	a: 1 # line
+	b: [2, 3]
+	`)
+	values := []string{}
+	err = walky.WalkPathMatchers(commented, func(n *yaml.Node) error {
+		values = append(values, n.Value)
+		return nil
+	}, walky.AnyMatcher(walky.WithComments()))
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "1", "", "2", "3"}, values)
+
 	err = walky.WalkPath(&root, expectedInt(2), "a", 1)
 	require.NoError(t, err)
 	require.True(t, matchFound)
@@ -467,3 +483,603 @@ bin: baz
 	//         value
 	//     - 42
 }
+
+func TestWalkComments(t *testing.T) {
+	doc := HereBytes(`
+	# head a
+	a: 1 # line a
+	b:
+		# head c
+		c: [2, 3] # line c
+		# foot c
+
+	# head d
+	d:
+		- 4 # line 4
+		- 5
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	collected := []string{}
+	err = walky.Walk(&root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		switch {
+		case current.Kind == walky.CommentNode:
+			owner := parent.Value
+			if owner == "" {
+				owner = walky.KindString(parent.Kind)
+			}
+			collected = append(collected, fmt.Sprintf("%s [%s]", current.Value, owner))
+		case current.Kind == yaml.ScalarNode:
+			collected = append(collected, current.Value)
+		}
+		return opts.MissStatus(), nil
+	}, walky.WithComments())
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"# head a [a]",
+		"a",
+		"# line a [1]",
+		"b",
+		"# head c [c]",
+		"c",
+		"# line c [sequence]",
+		"2",
+		"3",
+		"# foot c [c]",
+		"# head d [d]",
+		"d",
+		"4",
+		"# line 4 [4]",
+		"5",
+	}, collected)
+
+	// comments are not visited by default
+	err = walky.Walk(&root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		require.NotEqual(t, walky.CommentNode, current.Kind)
+		return opts.MissStatus(), nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWalkNodeBudget(t *testing.T) {
+	doc := HereBytes(`
+	a: [1, 2, 3]
+	b:
+		c: [4, 5]
+	`)
+	var root yaml.Node
+	err := yaml.Unmarshal(doc, &root)
+	require.NoError(t, err)
+
+	visited := 0
+	countNodes := func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		visited++
+		return opts.MissStatus(), nil
+	}
+
+	// root, a, 1, 2, 3, b, c, 4, 5
+	err = walky.Walk(&root, countNodes)
+	require.NoError(t, err)
+	require.Equal(t, 9, visited)
+
+	visited = 0
+	err = walky.Walk(&root, countNodes, walky.WithNodeBudget(9))
+	require.NoError(t, err)
+	require.Equal(t, 9, visited)
+
+	for _, budget := range []int{0, 1, 4, 8} {
+		visited = 0
+		err = walky.Walk(&root, countNodes, walky.WithNodeBudget(budget))
+		require.ErrorIs(t, err, walky.ErrNodeBudgetExceeded)
+		require.Equal(t, budget, visited)
+	}
+
+	visited = 0
+	err = walky.Walk(&root, countNodes, walky.WithNodeBudget(4), walky.WithMaxDepth(0))
+	require.NoError(t, err)
+	require.Equal(t, 3, visited)
+}
+
+func TestWalkPathCreateMissing(t *testing.T) {
+	root := parse(t, `
+	metadata:
+		name: web
+	empty:
+	list: [a]
+	`)
+	set := func(value string, path ...interface{}) {
+		t.Helper()
+		called := 0
+		err := walky.WalkPath(root, func(node *yaml.Node) error {
+			called++
+			walky.AssignNode(node, walky.NewStringNode(value))
+			return nil
+		}, append(path, walky.WithCreateMissing())...)
+		require.NoError(t, err)
+		require.Equal(t, 1, called)
+	}
+	set("demo", "metadata", "labels", "app")
+	set("prod", "metadata", "labels", "env")
+	set("api", "metadata", "name")
+	set("x", "empty", "nested")
+	set("b", "list", 1)
+	set("c", "servers", 0, "host")
+
+	got, err := yaml.Marshal(root)
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		metadata:
+			labels:
+				app: demo
+				env: prod
+			name: api
+		empty:
+			nested: x
+		list: [a, b]
+		servers:
+			- host: c
+	`), string(got))
+
+	// sequences are padded with nulls to reach the index
+	set("d", "servers", 2, "host")
+	got, err = yaml.Marshal(walky.GetKey(root, "servers"))
+	require.NoError(t, err)
+	require.Equal(t, Here(`
+		- host: c
+		-
+		- host: d
+	`), string(got))
+
+	// nodes of the wrong kind cannot be created
+	called := false
+	err = walky.WalkPath(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, "metadata", "name", "first", walky.WithCreateMissing())
+	require.EqualError(t, err, `line 2:11 at "api": expected node kind "mapping", got "scalar"`)
+	require.False(t, called)
+	err = walky.WalkPath(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, "list", -6, walky.WithCreateMissing())
+	require.EqualError(t, err, `line 4:7: sequence index -6 out of range`)
+	require.False(t, called)
+
+	// without the option nothing is created
+	err = walky.WalkPath(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, "missing", "key")
+	require.NoError(t, err)
+	require.False(t, called)
+	require.False(t, walky.HasKey(root, "missing"))
+}
+
+func TestWalkTracePath(t *testing.T) {
+	root := parse(t, `
+	a:
+		b:
+			c: [x, y, z]
+	d: 1
+	`)
+	traced := []string{}
+	err := walky.Walk(root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		return opts.MissStatus(), nil
+	}, walky.WithTracePath(func(path []interface{}, current, parent *yaml.Node, pos, depth int, ws walky.WalkStatus, err error) {
+		traced = append(traced, fmt.Sprintf("%s %s depth=%d pos=%d", walky.PathString(path...), current.Value, depth, pos))
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"  depth=0 pos=-1",
+		"a a depth=0 pos=0",
+		"a.b b depth=1 pos=0",
+		"a.b.c c depth=2 pos=0",
+		"a.b.c[0] x depth=3 pos=0",
+		"a.b.c[1] y depth=3 pos=1",
+		"a.b.c[2] z depth=3 pos=2",
+		"d d depth=0 pos=2",
+	}, traced)
+
+	// paths are retained correctly for breadth-first walks
+	paths := []string{}
+	err = walky.Walk(root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		return opts.MissStatus(), nil
+	}, walky.WithBreadthFirst(), walky.WithTracePath(func(path []interface{}, current, parent *yaml.Node, pos, depth int, ws walky.WalkStatus, err error) {
+		paths = append(paths, walky.PathString(path...))
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"", "a", "d", "a.b", "a.b.c", "a.b.c[0]", "a.b.c[1]", "a.b.c[2]"}, paths)
+}
+
+func TestWalkPropagatesErrors(t *testing.T) {
+	root := parse(t, `
+	a:
+		b:
+			c: [1, 2, invalid]
+	`)
+	errInvalid := errors.New("invalid value")
+	validate := func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		if current.Value == "invalid" {
+			return opts.MissStatus(), walky.NewYAMLError(errInvalid, current)
+		}
+		return opts.MissStatus(), nil
+	}
+
+	err := walky.Walk(root, validate)
+	require.ErrorIs(t, err, errInvalid)
+	require.EqualError(t, err, `line 3:19 at "invalid": invalid value`)
+
+	err = walky.Walk(root, validate, walky.WithBreadthFirst())
+	require.ErrorIs(t, err, errInvalid)
+	require.EqualError(t, err, `line 3:19 at "invalid": invalid value`)
+
+	// the node budget is also enforced for breadth-first walks
+	err = walky.Walk(root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		return opts.MissStatus(), nil
+	}, walky.WithBreadthFirst(), walky.WithNodeBudget(3))
+	require.ErrorIs(t, err, walky.ErrNodeBudgetExceeded)
+}
+
+func TestWalkContext(t *testing.T) {
+	root := parse(t, `
+	a: [1, 2, 3]
+	b:
+		c: [4, 5]
+	`)
+	for _, tt := range []struct {
+		name     string
+		opts     []walky.WalkOpt
+		cancelAt string
+		expected []string
+	}{{
+		name:     "depth first",
+		cancelAt: "2",
+		expected: []string{"", "a", "1", "2"},
+	}, {
+		name:     "breadth first",
+		opts:     []walky.WalkOpt{walky.WithBreadthFirst()},
+		cancelAt: "c",
+		expected: []string{"", "a", "b", "1", "2", "3", "c"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			visited := []string{}
+			err := walky.WalkContext(ctx, root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+				visited = append(visited, current.Value)
+				if current.Value == tt.cancelAt {
+					cancel()
+				}
+				return opts.MissStatus(), nil
+			}, tt.opts...)
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, tt.expected, visited)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := walky.WalkContext(ctx, root, func(current, parent *yaml.Node, pos int, opts *walky.WalkOptions) (walky.WalkStatus, error) {
+		t.Fatal("should not be called")
+		return opts.MissStatus(), nil
+	})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRegexMatcher(t *testing.T) {
+	root := parse(t, `
+	env_home: /home
+	env_path: /bin
+	other: value
+	services:
+		web:
+			env_port: 80
+			name: web
+		db:
+			env_port: 5432
+	`)
+	collect := func(path ...interface{}) []string {
+		t.Helper()
+		got := []string{}
+		err := walky.WalkPath(root, func(node *yaml.Node) error {
+			got = append(got, node.Value)
+			return nil
+		}, path...)
+		require.NoError(t, err)
+		return got
+	}
+	require.Equal(t, []string{"/home", "/bin"}, collect(regexp.MustCompile(`^env_.*`)))
+	require.Equal(t, []string{"80", "5432"}, collect("services", regexp.MustCompile(`.*`), regexp.MustCompile(`^env_`)))
+	require.Empty(t, collect("other", regexp.MustCompile(`.*`)))
+
+	got := []string{}
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		got = append(got, node.Value)
+		return nil
+	}, walky.StringMatcher("services"), walky.RegexMatcher(regexp.MustCompile(`^w`)), walky.StringMatcher("name"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"web"}, got)
+
+	// patterns cannot be created
+	called := false
+	err = walky.WalkPath(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, regexp.MustCompile(`^missing`), "key", walky.WithCreateMissing())
+	require.NoError(t, err)
+	require.False(t, called)
+
+	// nothing is created for a path element followed by a pattern
+	err = walky.WalkPath(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, "missing", regexp.MustCompile(`.*`), walky.WithCreateMissing())
+	require.NoError(t, err)
+	require.False(t, called)
+	require.False(t, walky.HasKey(root, "missing"))
+}
+
+func TestGlobMatcher(t *testing.T) {
+	root := parse(t, `
+	service-web: {port: 80}
+	service-db: {port: 5432}
+	services: {port: 1}
+	sidecar: {port: 9000}
+	list: [service-x]
+	`)
+	collect := func(matchers ...walky.PathMatcher) ([]string, error) {
+		got := []string{}
+		err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+			got = append(got, node.Value)
+			return nil
+		}, matchers...)
+		return got, err
+	}
+	got, err := collect(walky.GlobMatcher("service-*"), walky.StringMatcher("port"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"80", "5432"}, got)
+
+	got, err = collect(walky.GlobMatcher("s?????e*"), walky.StringMatcher("port"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"80", "5432", "1"}, got)
+
+	got, err = collect(walky.GlobMatcher("[a-s]idecar"), walky.StringMatcher("port"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"9000"}, got)
+
+	// non-mapping nodes are skipped
+	got, err = collect(walky.StringMatcher("list"), walky.GlobMatcher("*"))
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	_, err = collect(walky.GlobMatcher("[service"))
+	require.ErrorIs(t, err, path.ErrBadPattern)
+}
+
+func TestRecursiveMatcher(t *testing.T) {
+	root := parse(t, `
+	image: top
+	defaults: &defaults
+		image: default
+	spec:
+		containers:
+			- name: web
+			  image: web:1.0
+			- <<: *defaults
+			  name: sidecar
+			- *defaults
+		nested:
+			deeper:
+				image: deep
+	`)
+	images := []string{}
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		images = append(images, node.Value)
+		return nil
+	}, walky.RecursiveMatcher(walky.StringMatcher("image")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"top", "default", "web:1.0", "deep"}, images)
+
+	// recursive matches can be combined with other matchers
+	names := []string{}
+	err = walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		names = append(names, node.Value)
+		return nil
+	}, walky.StringMatcher("spec"), walky.RecursiveMatcher(walky.IndexMatcher(1)), walky.StringMatcher("name"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"sidecar"}, names)
+}
+
+func TestWalkPathNegativeIndex(t *testing.T) {
+	root := parse(t, `
+	list: [a, b, c]
+	nested:
+		- [x, y]
+		- [z]
+	`)
+	get := func(path ...interface{}) []string {
+		t.Helper()
+		got := []string{}
+		err := walky.WalkPath(root, func(node *yaml.Node) error {
+			got = append(got, node.Value)
+			return nil
+		}, path...)
+		require.NoError(t, err)
+		return got
+	}
+	require.Equal(t, []string{"c"}, get("list", -1))
+	require.Equal(t, []string{"b"}, get("list", -2))
+	require.Equal(t, []string{"a"}, get("list", -3))
+	require.Empty(t, get("list", -4))
+	require.Empty(t, get("list", -5))
+	require.Equal(t, []string{"z"}, get("nested", -1, -1))
+	require.Equal(t, []string{"x"}, get("nested", -2, 0))
+
+	got := []string{}
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		got = append(got, node.Value)
+		return nil
+	}, walky.StringMatcher("list"), walky.IndexMatcher(-1))
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, got)
+}
+
+func TestRangeMatcher(t *testing.T) {
+	root := parse(t, `
+	list: [a, b, c, d, e, f, g]
+	scalar: value
+	`)
+	get := func(start, end int) []string {
+		t.Helper()
+		got := []string{}
+		err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+			got = append(got, node.Value)
+			return nil
+		}, walky.StringMatcher("list"), walky.RangeMatcher(start, end))
+		require.NoError(t, err)
+		return got
+	}
+	require.Equal(t, []string{"c", "d", "e", "f"}, get(2, 6))
+	require.Equal(t, []string{"a"}, get(0, 1))
+	require.Equal(t, []string{"f", "g"}, get(5, 100))
+	require.Equal(t, []string{"e", "f", "g"}, get(4, walky.RangeToEnd))
+	require.Equal(t, []string{"f", "g"}, get(-2, walky.RangeToEnd))
+	require.Equal(t, []string{"a", "b", "c", "d", "e", "f"}, get(0, -1))
+	require.Empty(t, get(3, 3))
+	require.Empty(t, get(5, 2))
+	require.Empty(t, get(10, walky.RangeToEnd))
+
+	// bulk update elements 2 through 5
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		node.Value = strings.ToUpper(node.Value)
+		return nil
+	}, walky.StringMatcher("list"), walky.RangeMatcher(2, 6))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "C", "D", "E", "F", "g"}, get(0, walky.RangeToEnd))
+
+	called := false
+	err = walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		called = true
+		return nil
+	}, walky.StringMatcher("scalar"), walky.RangeMatcher(0, walky.RangeToEnd))
+	require.NoError(t, err)
+	require.False(t, called)
+}
+
+func TestPredicateMatcher(t *testing.T) {
+	root := parse(t, `
+	items:
+		- kind: Deployment
+		  name: web
+		- kind: Service
+		  name: web
+		- kind: Deployment
+		  name: api
+	byName:
+		web: {kind: Deployment}
+		db: {kind: StatefulSet}
+	`)
+	isDeployment := walky.PredicateMatcher(func(n *yaml.Node) bool {
+		kind := walky.GetKey(n, "kind")
+		return kind != nil && kind.Value == "Deployment"
+	})
+	got := []int{}
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		got = append(got, node.Line)
+		return nil
+	}, walky.StringMatcher("items"), isDeployment)
+	require.NoError(t, err)
+	require.Equal(t, []int{2, 6}, got)
+
+	// map values are passed to the predicate
+	got = []int{}
+	err = walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		require.Equal(t, yaml.MappingNode, node.Kind)
+		got = append(got, node.Line)
+		return nil
+	}, walky.StringMatcher("byName"), isDeployment)
+	require.NoError(t, err)
+	require.Equal(t, []int{9}, got)
+
+	err = walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		return fmt.Errorf("stop")
+	}, walky.StringMatcher("items"), isDeployment)
+	require.EqualError(t, err, "stop")
+}
+
+func TestTypeMatcher(t *testing.T) {
+	root := parse(t, `
+	ports: &ports [80, 443]
+	config:
+		name: web
+		replicas: 3
+		ports: *ports
+		labels: {app: web}
+		version: "1.0"
+		build: 42
+		empty: ~
+	`)
+	collect := func(m walky.PathMatcher) []int {
+		t.Helper()
+		got := []int{}
+		err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+			got = append(got, node.Line)
+			return nil
+		}, walky.StringMatcher("config"), m)
+		require.NoError(t, err)
+		return got
+	}
+	// the alias on line 5 resolves to the sequence on line 1
+	require.Equal(t, []int{1}, collect(walky.TypeMatcher(yaml.SequenceNode)))
+	require.Equal(t, []int{6}, collect(walky.TypeMatcher(yaml.MappingNode)))
+	require.Equal(t, []int{3, 4, 7, 8, 9}, collect(walky.TypeMatcher(yaml.ScalarNode)))
+	require.Empty(t, collect(walky.TypeMatcher(yaml.AliasNode)))
+
+	require.Equal(t, []int{3, 7}, collect(walky.TagMatcher("!!str")))
+	require.Equal(t, []int{4, 8}, collect(walky.TagMatcher("!!int")))
+	require.Equal(t, []int{4, 8}, collect(walky.TagMatcher("tag:yaml.org,2002:int")))
+	require.Equal(t, []int{9}, collect(walky.TagMatcher("!!null")))
+	require.Equal(t, []int{1}, collect(walky.TagMatcher("!!seq")))
+
+	// quote every numeric looking string
+	err := walky.WalkPathMatchers(root, func(node *yaml.Node) error {
+		node.Style = yaml.DoubleQuotedStyle
+		node.Tag = "!!str"
+		return nil
+	}, walky.StringMatcher("config"), walky.TagMatcher("!!int"))
+	require.NoError(t, err)
+	require.Equal(t, []int{3, 4, 7, 8}, collect(walky.TagMatcher("!!str")))
+}
+
+func TestKeysWalker(t *testing.T) {
+	root := parse(t, `
+	# comment
+	defaults: &defaults
+		timeout: 30
+	services:
+		- name: web
+		  <<: *defaults
+		  ports: [80, {internal: 8080}]
+	`)
+	type key struct {
+		Value string
+		Line  int
+	}
+	got := []key{}
+	err := walky.Walk(root, walky.KeysWalker(func(node *yaml.Node) error {
+		got = append(got, key{node.Value, node.Line})
+		return nil
+	}), walky.WithComments())
+	require.NoError(t, err)
+	require.Equal(t, []key{
+		{"defaults", 2},
+		{"timeout", 3},
+		{"services", 4},
+		{"name", 5},
+		{"<<", 6},
+		{"ports", 7},
+		{"internal", 7},
+	}, got)
+}